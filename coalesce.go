@@ -0,0 +1,180 @@
+package blink1
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CoalescingStats represents the statistics of color updates in coalescing mode of Controller.
+type CoalescingStats struct {
+	Requested uint64 // Number of color updates requested by PlayColor, PlayRGB and PlayHSB
+	Sent      uint64 // Number of color updates sent to the device
+	Coalesced uint64 // Number of color updates dropped since they were replaced by newer ones before being sent
+	Failed    uint64 // Number of color updates failed to be sent to the device
+}
+
+func (st CoalescingStats) String() string {
+	return fmt.Sprintf("🧮{requested=%d sent=%d coalesced=%d failed=%d}", st.Requested, st.Sent, st.Coalesced, st.Failed)
+}
+
+// coalescer keeps the latest pending color update and sends it to the device at the limited rate.
+type coalescer struct {
+	mu       sync.Mutex
+	interval time.Duration
+	pending  bool
+	r, g, b  byte
+	stats    CoalescingStats
+
+	kickCh chan struct{} // signaled when a color update is pending
+	quitCh chan struct{} // closed to stop the worker
+	doneCh chan struct{} // closed when the worker quits
+}
+
+// SetCoalescing enables the coalescing mode of the controller with the given minimum interval between color updates, or disables it if the interval is not positive.
+// Default is disabled.
+//
+// In coalescing mode, PlayColor, PlayRGB and PlayHSB return immediately without waiting for the USB write, only the latest color is sent to the device at most once per interval,
+// and the intermediate colors are dropped. It's useful for mapping a streaming metric to the color at high frequency.
+// The pending color is sent before coalescing mode is disabled or the controller is closed. Errors of sending are counted in the stats only.
+func (c *Controller) SetCoalescing(interval time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// flush the pending color and stop the current worker, which quits without waiting for c.mu
+	if co := c.coal; co != nil {
+		c.flushCoalesced(co)
+		c.coal = nil
+		close(co.quitCh)
+		co.mu.Lock()
+		c.coalStats = co.stats
+		co.mu.Unlock()
+	}
+	if interval <= 0 || c.closed {
+		return
+	}
+
+	// start a new worker, and keep the stats
+	co := &coalescer{
+		interval: interval,
+		stats:    c.coalStats,
+		kickCh:   make(chan struct{}, 1),
+		quitCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+	c.coal = co
+	c.workers.Add(1)
+	go c.coalesce(co)
+}
+
+// GetCoalescingStats returns the statistics of color updates since coalescing mode was first enabled.
+func (c *Controller) GetCoalescingStats() CoalescingStats {
+	c.mu.Lock()
+	co, stats := c.coal, c.coalStats
+	c.mu.Unlock()
+	if co == nil {
+		return stats
+	}
+
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	return co.stats
+}
+
+// playRGB sets all LEDs to the specified RGB color immediately, or leaves it to the coalescing worker if coalescing mode is enabled.
+func (c *Controller) playRGB(ctx context.Context, r, g, b byte) error {
+	if co := c.coal; co != nil {
+		if c.closed {
+			return ErrClosed
+		}
+		co.push(r, g, b)
+		return nil
+	}
+	return c.dev.SetRGBNowContext(ctx, r, g, b, LEDAll)
+}
+
+// push replaces the pending color with the given one and wakes up the worker.
+func (co *coalescer) push(r, g, b byte) {
+	co.mu.Lock()
+	co.stats.Requested++
+	if co.pending {
+		co.stats.Coalesced++
+	}
+	co.pending = true
+	co.r, co.g, co.b = r, g, b
+	co.mu.Unlock()
+
+	select {
+	case co.kickCh <- struct{}{}:
+	default:
+	}
+}
+
+// coalesce sends the pending color to the device at most once per interval until the worker is stopped or the controller is closed.
+func (c *Controller) coalesce(co *coalescer) {
+	defer c.workers.Done()
+	defer close(co.doneCh)
+
+	// flush under c.mu to keep the order with other commands, and skip if the worker is already replaced
+	flush := func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.coal == co {
+			c.flushCoalesced(co)
+		}
+	}
+	for {
+		select {
+		case <-co.kickCh:
+		case <-co.quitCh:
+			return
+		case <-c.closeCh:
+			flush()
+			return
+		}
+		flush()
+
+		// wait for the interval, updates in between are coalesced
+		t := time.NewTimer(co.interval)
+		select {
+		case <-t.C:
+		case <-co.quitCh:
+			t.Stop()
+			return
+		case <-c.closeCh:
+			t.Stop()
+			flush()
+			return
+		}
+	}
+}
+
+// settleCoalesced sends the pending color of coalescing mode before any other command, so it can't undo the command later.
+// It must be called with c.mu held, and it does nothing if coalescing mode is disabled.
+func (c *Controller) settleCoalesced() {
+	if co := c.coal; co != nil {
+		c.flushCoalesced(co)
+	}
+}
+
+// flushCoalesced sends the pending color to the device if there is one. It must be called with c.mu held.
+// The color is sent regardless of the context of the caller, since it's the result of an earlier command which has already returned.
+func (c *Controller) flushCoalesced(co *coalescer) {
+	co.mu.Lock()
+	pending, r, g, b := co.pending, co.r, co.g, co.b
+	co.pending = false
+	co.mu.Unlock()
+	if !pending {
+		return
+	}
+
+	err := c.dev.SetRGBNowContext(context.Background(), r, g, b, LEDAll)
+	co.mu.Lock()
+	if err != nil {
+		co.stats.Failed++
+	} else {
+		co.stats.Sent++
+	}
+	co.mu.Unlock()
+}
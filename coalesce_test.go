@@ -0,0 +1,100 @@
+package blink1_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	b1 "github.com/b1ug/blink1-go"
+)
+
+func TestController_Coalescing(t *testing.T) {
+	c := newEmulatedController(t, 2)
+	c.SetCoalescing(50 * time.Millisecond)
+
+	// burst of updates: the last one wins
+	for i := 0; i < 100; i++ {
+		if err := c.PlayRGB(byte(i), 0, 0); err != nil {
+			t.Fatalf("PlayRGB() got unexpected error: %v", err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	if cl, _ := c.ReadColor(b1.LED1); b1.ColorToHex(cl) != "#630000" {
+		t.Errorf("ReadColor() after burst = %v, want #630000", b1.ColorToHex(cl))
+	}
+	st := c.GetCoalescingStats()
+	if st.Requested != 100 || st.Sent < 1 || st.Sent+st.Coalesced != 100 || st.Failed != 0 {
+		t.Errorf("GetCoalescingStats() = %v, want 100 requested and the most coalesced", st)
+	}
+
+	// disable: pending color is flushed, and stats are kept
+	_ = c.PlayHSB(240, 100, 100)
+	_ = c.PlayColor(b1.ColorGreen)
+	c.SetCoalescing(0)
+	if cl, _ := c.ReadColor(b1.LED1); b1.ColorToHex(cl) != "#00FF00" {
+		t.Errorf("ReadColor() after disabling = %v, want #00FF00", b1.ColorToHex(cl))
+	}
+	if got := c.GetCoalescingStats(); got.Requested != 102 {
+		t.Errorf("GetCoalescingStats() after disabling = %v, want 102 requested", got)
+	}
+
+	// close: pending color is flushed
+	c.SetCoalescing(time.Second)
+	_ = c.PlayColor(b1.ColorRed)
+	_ = c.PlayColor(b1.ColorBlue)
+	c.Close()
+	if err := c.PlayColor(b1.ColorRed); !errors.Is(err, b1.ErrClosed) {
+		t.Errorf("PlayColor() after Close got error: %v, want ErrClosed", err)
+	}
+	if got := c.GetCoalescingStats(); got.Sent+got.Coalesced != 104 {
+		t.Errorf("GetCoalescingStats() after Close = %v, want all 104 handled", got)
+	}
+}
+
+func TestController_CoalescingOrder(t *testing.T) {
+	c := newEmulatedController(t, 2)
+	defer c.Close()
+	c.SetCoalescing(100 * time.Millisecond)
+
+	// the first color is sent at once, the second one is pending within the interval
+	_ = c.PlayColor(b1.ColorRed)
+	time.Sleep(10 * time.Millisecond)
+	_ = c.PlayColor(b1.ColorBlue)
+
+	// the pending color must not undo the later command
+	if err := c.StopPlaying(); err != nil {
+		t.Fatalf("StopPlaying() got unexpected error: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	for _, led := range []b1.LEDIndex{b1.LED1, b1.LED2} {
+		if cl, err := c.ReadColor(led); err != nil || b1.ColorToHex(cl) != "#000000" {
+			t.Errorf("ReadColor(%v) after StopPlaying() = %v, %v, want #000000", led, b1.ColorToHex(cl), err)
+		}
+	}
+	if st := c.GetCoalescingStats(); st.Requested != 2 || st.Sent != 2 {
+		t.Errorf("GetCoalescingStats() = %v, want 2 requested and 2 sent", st)
+	}
+}
+
+func TestController_CoalescingCanceledContext(t *testing.T) {
+	c := newEmulatedController(t, 2)
+	defer c.Close()
+	c.SetCoalescing(time.Second)
+
+	// the pending color is kept even if the next caller's context is canceled
+	_ = c.PlayColor(b1.ColorRed)
+	time.Sleep(10 * time.Millisecond)
+	_ = c.PlayColor(b1.ColorBlue)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.ReadColorContext(ctx, b1.LED1); !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadColorContext() with canceled context got error: %v, want context.Canceled", err)
+	}
+	if cl, err := c.ReadColor(b1.LED1); err != nil || b1.ColorToHex(cl) != "#0000FF" {
+		t.Errorf("ReadColor() after canceled call = %v, %v, want #0000FF", b1.ColorToHex(cl), err)
+	}
+	if st := c.GetCoalescingStats(); st.Sent != 2 || st.Failed != 0 {
+		t.Errorf("GetCoalescingStats() = %v, want 2 sent and none failed", st)
+	}
+}
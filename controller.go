@@ -1,8 +1,10 @@
 package blink1
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	hid "github.com/b1ug/gid"
 )
@@ -11,8 +13,24 @@ import (
 type Controller struct {
 	mu     sync.Mutex
 	dev    *Device
+	fw     *FirmwareInfo // cached firmware info, read from the device on demand
 	gamma  bool
-	quitCh chan struct{}
+	retry  RetryPolicy   // retry policy for operations on pattern lines
+	pacing time.Duration // gap between consecutive commands
+	quitCh chan struct{} // closed to stop the auto tickle worker
+	doneCh chan struct{} // closed when the auto tickle worker quits
+
+	// command queue
+	queue cmdQueue
+
+	// coalescing mode
+	coal      *coalescer      // nil if disabled
+	coalStats CoalescingStats // stats of the stopped coalescing worker
+
+	// lifecycle
+	closed  bool
+	closeCh chan struct{}  // closed when the controller is closed, to stop all background workers
+	workers sync.WaitGroup // background tickle workers and the dispatcher of command queue
 }
 
 // OpenController opens a blink(1) controller for device which is connected to the system.
@@ -21,12 +39,32 @@ func OpenController(info *hid.DeviceInfo) (*Controller, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Controller{dev: dev, gamma: true}, nil
+	return newController(dev), nil
+}
+
+// NewControllerWithTransport creates a blink(1) controller for device behind the given transport.
+func NewControllerWithTransport(tr Transport) (*Controller, error) {
+	dev, err := NewDevice(tr)
+	if err != nil {
+		return nil, err
+	}
+	return newController(dev), nil
 }
 
 // NewController creates a blink(1) controller for existing device instance.
 func NewController(dev *Device) *Controller {
-	return &Controller{dev: dev, gamma: true}
+	return newController(dev)
+}
+
+// newController creates a controller instance with the given device.
+func newController(dev *Device) *Controller {
+	return &Controller{
+		dev:     dev,
+		gamma:   true,
+		retry:   DefaultRetryPolicy(),
+		pacing:  opsInterval,
+		closeCh: make(chan struct{}),
+	}
 }
 
 func (c *Controller) String() string {
@@ -38,11 +76,30 @@ func (c *Controller) GetDevice() *Device {
 	return c.dev
 }
 
-// Close closes the device and release the kept resources.
+// Close stops all the background tickle workers and the command queue and waits for them to quit, then closes the device and release the kept resources.
+// It's safe to call Close multiple times, and operations after Close will return ErrClosed.
 func (c *Controller) Close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	c.queue.close()
+	close(c.closeCh)
+	c.mu.Unlock()
+
+	// wait for workers to turn off tickle before closing the device
+	c.workers.Wait()
 	c.dev.Close()
 }
 
+// SetAutoReconnect enables or disables the resilient mode of the underlying device, which is disabled by default.
+// In resilient mode, the device will be reopened and the last known state will be replayed after it's unplugged and re-plugged. See Device.SetAutoReconnect() for details.
+func (c *Controller) SetAutoReconnect(on bool) {
+	c.dev.SetAutoReconnect(on)
+}
+
 // SetGammaCorrection sets the gamma correction on/off for the controller. Default is on.
 // If it is true, the gamma correction will be applied for state and pattern while playing or writing.
 func (c *Controller) SetGammaCorrection(on bool) {
@@ -50,3 +107,56 @@ func (c *Controller) SetGammaCorrection(on bool) {
 	defer c.mu.Unlock()
 	c.gamma = on
 }
+
+// GetFirmwareInfo returns the firmware info of the device, it's read from the device once and cached for the controller.
+func (c *Controller) GetFirmwareInfo() (FirmwareInfo, error) {
+	return c.GetFirmwareInfoContext(context.Background())
+}
+
+// GetFirmwareInfoContext works like GetFirmwareInfo with the given context.
+func (c *Controller) GetFirmwareInfoContext(ctx context.Context) (FirmwareInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.firmware(ctx)
+}
+
+// GetCapabilities returns the set of features supported by the firmware of the device.
+func (c *Controller) GetCapabilities() (Capabilities, error) {
+	return c.GetCapabilitiesContext(context.Background())
+}
+
+// GetCapabilitiesContext works like GetCapabilities with the given context.
+func (c *Controller) GetCapabilitiesContext(ctx context.Context) (Capabilities, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fi, err := c.firmware(ctx)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	return fi.Capabilities(), nil
+}
+
+// firmware returns the cached firmware info, or reads it from the device if it's not cached yet.
+func (c *Controller) firmware(ctx context.Context) (FirmwareInfo, error) {
+	if c.fw == nil {
+		fi, err := c.dev.GetFirmwareInfoContext(ctx)
+		if err != nil {
+			return fi, fmt.Errorf("b1: failed to get firmware info: %w", err)
+		}
+		c.fw = &fi
+	}
+	return *c.fw, nil
+}
+
+// requireCapability returns an UnsupportedError if the feature is not supported by the firmware of the device.
+func (c *Controller) requireCapability(ctx context.Context, feature string, supported func(cp Capabilities) bool) error {
+	fi, err := c.firmware(ctx)
+	if err != nil {
+		return err
+	}
+	if !supported(fi.Capabilities()) {
+		return &UnsupportedError{Feature: feature, Firmware: fi}
+	}
+	return nil
+}
@@ -1,53 +1,76 @@
 package blink1
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"image/color"
 	"time"
 )
 
-var (
-	errInvalidPosition    = errors.New("b1: invalid pattern position")
-	errInvalidRepeatTimes = errors.New("b1: invalid pattern repeat times")
-	errInvalidTimeout     = errors.New("b1: invalid timeout")
-)
-
-// GetFirmwareVersion returns the firmware version of the device.
+// GetFirmwareVersion returns the firmware version of the device, e.g. 204 for v204.
 func (c *Controller) GetFirmwareVersion() (int, error) {
-	return c.dev.GetVersion()
+	return c.GetFirmwareVersionContext(context.Background())
+}
+
+// GetFirmwareVersionContext works like GetFirmwareVersion with the given context.
+func (c *Controller) GetFirmwareVersionContext(ctx context.Context) (int, error) {
+	fi, err := c.GetFirmwareInfoContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return fi.Version(), nil
 }
 
 // PlayStateBlocking fades the given LED to the specified RGB color over the specified time, and blocks until the fade is finished.
 func (c *Controller) PlayStateBlocking(st LightState) error {
+	return c.PlayStateBlockingContext(context.Background(), st)
+}
+
+// PlayStateBlockingContext works like PlayStateBlocking with the given context.
+func (c *Controller) PlayStateBlockingContext(ctx context.Context, st LightState) error {
 	// NOTE: no lock here, since PlayState will lock
 	// play state
-	if err := c.PlayState(st); err != nil {
+	if err := c.PlayStateContext(ctx, st); err != nil {
 		return err
 	}
 
 	// block until fade is finished
-	if dur := convDurationToActual(st.FadeTime); dur > 0 {
-		time.Sleep(dur)
-	}
-	return nil
+	return sleepContext(ctx, convDurationToActual(st.FadeTime))
 }
 
 // PlayState fades the given LED to the specified RGB color over the specified time.
+// An UnsupportedError will be returned if a single LED is addressed on a device without per-LED addressing.
 func (c *Controller) PlayState(st LightState) error {
+	return c.PlayStateContext(context.Background(), st)
+}
+
+// PlayStateContext works like PlayState with the given context.
+func (c *Controller) PlayStateContext(ctx context.Context, st LightState) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.settleCoalesced()
+
+	if st.LED != LEDAll {
+		if err := c.requireCapability(ctx, "per-LED addressing", hasPerLEDAddressing); err != nil {
+			return err
+		}
+	}
 
 	r, g, b := convColorToRGB(st.Color)
 	if c.gamma {
 		r, g, b = degammaRGB(r, g, b)
 	}
 	msec := uint(st.FadeTime.Milliseconds())
-	return c.dev.FadeToRGB(r, g, b, msec, st.LED)
+	return c.dev.FadeToRGBContext(ctx, r, g, b, msec, st.LED)
 }
 
 // PlayColor fades the all LED to the specified RGB color immediately.
 func (c *Controller) PlayColor(cl color.Color) error {
+	return c.PlayColorContext(context.Background(), cl)
+}
+
+// PlayColorContext works like PlayColor with the given context.
+func (c *Controller) PlayColorContext(ctx context.Context, cl color.Color) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -55,21 +78,31 @@ func (c *Controller) PlayColor(cl color.Color) error {
 	if c.gamma {
 		r, g, b = degammaRGB(r, g, b)
 	}
-	return c.dev.SetRGBNow(r, g, b, LEDAll)
+	return c.playRGB(ctx, r, g, b)
 }
 
 // PlayRGB fades the all LED to the specified RGB color immediately.
 func (c *Controller) PlayRGB(r, g, b byte) error {
+	return c.PlayRGBContext(context.Background(), r, g, b)
+}
+
+// PlayRGBContext works like PlayRGB with the given context.
+func (c *Controller) PlayRGBContext(ctx context.Context, r, g, b byte) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	return c.dev.SetRGBNow(r, g, b, LEDAll)
+	return c.playRGB(ctx, r, g, b)
 }
 
 // PlayHSB fades the all LED to the specified HSB/HSV color immediately.
 // Valid hue range is [0, 360], saturation range and brightness/value range is [0, 100].
 // Values outside of the valid range will be clamped to the range.
 func (c *Controller) PlayHSB(hue, saturation, brightness float64) error {
+	return c.PlayHSBContext(context.Background(), hue, saturation, brightness)
+}
+
+// PlayHSBContext works like PlayHSB with the given context.
+func (c *Controller) PlayHSBContext(ctx context.Context, hue, saturation, brightness float64) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -77,15 +110,21 @@ func (c *Controller) PlayHSB(hue, saturation, brightness float64) error {
 	if c.gamma {
 		r, g, b = degammaRGB(r, g, b)
 	}
-	return c.dev.SetRGBNow(r, g, b, LEDAll)
+	return c.playRGB(ctx, r, g, b)
 }
 
 // ReadColor reads the current color of the specified LED.
 func (c *Controller) ReadColor(ledN LEDIndex) (color.Color, error) {
+	return c.ReadColorContext(context.Background(), ledN)
+}
+
+// ReadColorContext works like ReadColor with the given context.
+func (c *Controller) ReadColorContext(ctx context.Context, ledN LEDIndex) (color.Color, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.settleCoalesced()
 
-	r, g, b, err := c.dev.ReadRGB(ledN)
+	r, g, b, err := c.dev.ReadRGBContext(ctx, ledN)
 	if err != nil {
 		return nil, fmt.Errorf("b1: failed to read rgb: %w", err)
 	}
@@ -95,78 +134,95 @@ func (c *Controller) ReadColor(ledN LEDIndex) (color.Color, error) {
 // PlayPatternBlocking plays the given pattern, and blocks until the pattern is finished. It may block forever if the pattern is set to loop forever.
 // If the pattern has no states, it will only play the pattern without writing states to the device's RAM, and blocks until the pattern is finished.
 func (c *Controller) PlayPatternBlocking(pt Pattern) error {
+	return c.PlayPatternBlockingContext(context.Background(), pt)
+}
+
+// PlayPatternBlockingContext works like PlayPatternBlocking with the given context.
+func (c *Controller) PlayPatternBlockingContext(ctx context.Context, pt Pattern) error {
 	// NOTE: no lock here, since PlayPattern will lock
 	// play pattern
-	if err := c.PlayPattern(pt); err != nil {
+	if err := c.PlayPatternContext(ctx, pt); err != nil {
 		return err
 	}
 
 	// block until pattern is finished
 	if pt.RepeatTimes == 0 {
-		// infinite loop, block forever
-		<-make(chan struct{})
-	} else {
-		// otherwise read pattern to get total duration
-		startPos, endPos := pt.StartPosition, pt.EndPosition
-		if endPos == 0 {
-			endPos = getMaxPattern(c.dev.gen) - 1
-		}
-		// read pattern to get total duration
-		var totalDur time.Duration
-		for i := startPos; i <= endPos; i++ {
-			var st DeviceLightState
-			if err := retryWorkload(func() (ie error) {
-				st, ie = c.dev.ReadPatternLine(i)
-				return ie
-			}); err == nil {
-				totalDur += time.Duration(st.FadeTimeMsec) * time.Millisecond
-			} else {
-				return fmt.Errorf("b1: failed to read pattern line %d: %w", i, err)
-			}
+		// infinite loop, block until the context is done
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	// otherwise read pattern to get total duration
+	startPos, endPos := pt.StartPosition, pt.EndPosition
+	if endPos == 0 {
+		endPos = getMaxPattern(c.dev.gen) - 1
+	}
+	retry := c.GetRetryPolicy()
+	var totalDur time.Duration
+	for i := startPos; i <= endPos; i++ {
+		var st DeviceLightState
+		if err := retry.run(ctx, func(ctx context.Context) (ie error) {
+			st, ie = c.dev.ReadPatternLineContext(ctx, i)
+			return ie
+		}); err != nil {
+			return fmt.Errorf("b1: failed to read pattern line %d: %w", i, err)
 		}
-		// sleep for total duration
-		time.Sleep(totalDur * time.Duration(pt.RepeatTimes))
+		totalDur += time.Duration(st.FadeTimeMsec) * time.Millisecond
 	}
-	return nil
+
+	// sleep for total duration
+	return sleepContext(ctx, totalDur*time.Duration(pt.RepeatTimes))
 }
 
 // PlayPattern plays the given pattern. If the pattern has no states, it will only play the pattern without writing states to the device's RAM
 func (c *Controller) PlayPattern(pt Pattern) error {
+	return c.PlayPatternContext(context.Background(), pt)
+}
+
+// PlayPatternContext works like PlayPattern with the given context.
+func (c *Controller) PlayPatternContext(ctx context.Context, pt Pattern) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.settleCoalesced()
 
 	// ensure range is valid
 	if !c.isPosRangeValid(pt.StartPosition, pt.EndPosition) {
-		return errInvalidPosition
+		return ErrInvalidPosition
 	}
 	if pt.RepeatTimes > maxRepeat {
-		return errInvalidRepeatTimes
+		return ErrInvalidRepeatTimes
 	}
 	if pt.EndPosition == 0 {
 		pt.EndPosition = getMaxPattern(c.dev.gen) - 1
 	}
 
 	// load pattern to RAM
-	if err := c.loadStateSequence(pt.StartPosition, pt.EndPosition, pt.Sequence); err != nil {
+	if err := c.loadStateSequence(ctx, pt.StartPosition, pt.EndPosition, pt.Sequence); err != nil {
 		return err
 	}
 
 	// play pattern
-	return c.dev.PlayLoop(true, pt.StartPosition, pt.EndPosition, pt.RepeatTimes)
+	return c.dev.PlayLoopContext(ctx, true, pt.StartPosition, pt.EndPosition, pt.RepeatTimes)
 }
 
 // LoadPattern writes the given pattern to the device's RAM and it will be lost after the device is powered off.
 // To save the pattern to the device's flash, call WritePattern() after calling this function.
 func (c *Controller) LoadPattern(posStart, posEnd uint, seq StateSequence) error {
+	return c.LoadPatternContext(context.Background(), posStart, posEnd, seq)
+}
+
+// LoadPatternContext works like LoadPattern with the given context.
+func (c *Controller) LoadPatternContext(ctx context.Context, posStart, posEnd uint, seq StateSequence) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.settleCoalesced()
 
 	// load pattern to RAM
-	return c.loadStateSequence(posStart, posEnd, seq)
+	return c.loadStateSequence(ctx, posStart, posEnd, seq)
 }
 
 // loadStateSequence loads the given pattern to the device's RAM.
-func (c *Controller) loadStateSequence(posStart, posEnd uint, seq StateSequence) error {
+func (c *Controller) loadStateSequence(ctx context.Context, posStart, posEnd uint, seq StateSequence) error {
 	sc := len(seq) // sc for state counter
 	if sc == 0 {
 		// no states, just do nothing
@@ -174,12 +230,21 @@ func (c *Controller) loadStateSequence(posStart, posEnd uint, seq StateSequence)
 	}
 	if !c.isPosRangeValid(posStart, posEnd) {
 		// ensure range is valid
-		return errInvalidPosition
+		return ErrInvalidPosition
 	}
 	if posEnd == 0 {
 		// set posEnd to patt_max-1 if posEnd == 0
 		posEnd = getMaxPattern(c.dev.gen) - 1
 	}
+	for _, st := range seq {
+		// ensure states addressing a single LED are supported
+		if st.LED != LEDAll {
+			if err := c.requireCapability(ctx, "per-LED addressing", hasPerLEDAddressing); err != nil {
+				return err
+			}
+			break
+		}
+	}
 
 	// set patterns
 	pc := 0 // pc for position counter
@@ -191,8 +256,8 @@ func (c *Controller) loadStateSequence(posStart, posEnd uint, seq StateSequence)
 		}
 
 		// operate on device
-		if err := retryWorkload(func() error {
-			return c.dev.SetPatternLine(pos, st)
+		if err := c.retry.run(ctx, func(ctx context.Context) error {
+			return c.dev.SetPatternLineContext(ctx, pos, st)
 		}); err != nil {
 			return fmt.Errorf("b1: failed to set pattern line %d: %w", pos, err)
 		}
@@ -203,21 +268,34 @@ func (c *Controller) loadStateSequence(posStart, posEnd uint, seq StateSequence)
 		}
 
 		// sleep for a little while to avoid hardware errors
-		time.Sleep(opsInterval)
+		if err := sleepContext(ctx, c.pacing); err != nil {
+			return err
+		}
+
+		// give way to the queued commands with higher priority, the rest of lines must not override them
+		if c.queue.preempted(ctx) {
+			return fmt.Errorf("b1: upload aborted before pattern line %d: %w", pos+1, ErrPreempted)
+		}
 	}
 	return nil
 }
 
 // ReadPattern reads the current pattern in the device's RAM.
 func (c *Controller) ReadPattern() (StateSequence, error) {
+	return c.ReadPatternContext(context.Background())
+}
+
+// ReadPatternContext works like ReadPattern with the given context.
+func (c *Controller) ReadPatternContext(ctx context.Context) (StateSequence, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.settleCoalesced()
 
 	var ls StateSequence
 	for pos, posMax := uint(0), getMaxPattern(c.dev.gen); pos < posMax; pos++ {
 		var st DeviceLightState
-		if err := retryWorkload(func() (ie error) {
-			st, ie = c.dev.ReadPatternLine(pos)
+		if err := c.retry.run(ctx, func(ctx context.Context) (ie error) {
+			st, ie = c.dev.ReadPatternLineContext(ctx, pos)
 			return ie
 		}); err != nil {
 			return nil, fmt.Errorf("b1: failed to read pattern line %d: %w", pos, err)
@@ -229,18 +307,30 @@ func (c *Controller) ReadPattern() (StateSequence, error) {
 
 // WritePattern writes the pattern stored in the device's RAM to its flash. For mk2 device, only the first 16 patterns can be saved.
 func (c *Controller) WritePattern() error {
+	return c.WritePatternContext(context.Background())
+}
+
+// WritePatternContext works like WritePattern with the given context.
+func (c *Controller) WritePatternContext(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.settleCoalesced()
 
-	return c.dev.SavePattern()
+	return c.dev.SavePatternContext(ctx)
 }
 
 // IsPatternPlaying returns true if the pattern is playing.
 func (c *Controller) IsPatternPlaying() (bool, error) {
+	return c.IsPatternPlayingContext(context.Background())
+}
+
+// IsPatternPlayingContext works like IsPatternPlaying with the given context.
+func (c *Controller) IsPatternPlayingContext(ctx context.Context) (bool, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.settleCoalesced()
 
-	st, err := c.dev.ReadPlaystate()
+	st, err := c.dev.ReadPlaystateContext(ctx)
 	if err != nil {
 		return false, fmt.Errorf("b1: failed to read play state: %w", err)
 	}
@@ -249,10 +339,16 @@ func (c *Controller) IsPatternPlaying() (bool, error) {
 
 // GetPatternState returns the current state of the pattern that is playing.
 func (c *Controller) GetPatternState() (PatternState, error) {
+	return c.GetPatternStateContext(context.Background())
+}
+
+// GetPatternStateContext works like GetPatternState with the given context.
+func (c *Controller) GetPatternStateContext(ctx context.Context) (PatternState, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.settleCoalesced()
 
-	st, err := c.dev.ReadPlaystate()
+	st, err := c.dev.ReadPlaystateContext(ctx)
 	if err != nil {
 		return PatternState{}, err
 	}
@@ -270,10 +366,16 @@ func (c *Controller) GetPatternState() (PatternState, error) {
 // If the pattern is not playing, it only turns off all the LEDs.
 // It will NOT stop the auto/manual tickle.
 func (c *Controller) StopPlaying() error {
+	return c.StopPlayingContext(context.Background())
+}
+
+// StopPlayingContext works like StopPlaying with the given context.
+func (c *Controller) StopPlayingContext(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.settleCoalesced()
 
-	return c.dev.SetTickleMode(false, false, 0, 0, 0)
+	return c.dev.SetTickleModeContext(ctx, false, false, 0, 0, 0)
 }
 
 // StartAutoTickle sets the device to automatically tickle every 2 seconds.
@@ -282,50 +384,73 @@ func (c *Controller) StopPlaying() error {
 //
 // To stop the auto tickle, call StopAutoTickle().
 func (c *Controller) StartAutoTickle(posStart, posEnd uint, keepOld bool) error {
+	return c.StartAutoTickleContext(context.Background(), posStart, posEnd, keepOld)
+}
+
+// StartAutoTickleContext works like StartAutoTickle with the given context, the auto tickle also stops when the context is done.
+func (c *Controller) StartAutoTickleContext(ctx context.Context, posStart, posEnd uint, keepOld bool) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.settleCoalesced()
 
 	// ensure range is valid
 	if !c.isPosRangeValid(posStart, posEnd) {
-		return errInvalidPosition
+		return ErrInvalidPosition
+	}
+	if c.closed {
+		return ErrClosed
 	}
 
 	// if already started, stop it first
-	if c.quitCh != nil {
-		close(c.quitCh)
-	}
+	c.stopAutoTickle()
 
 	// prepare timeout ticker
 	timeout := 2 * time.Second
 	timeoutMsec := uint(timeout.Milliseconds())
 	timeoutMsec += timeoutMsec >> 1 // add 50% to timeout
 	ticker := time.NewTicker(timeout)
-	c.quitCh = make(chan struct{})
+	quitCh, doneCh := make(chan struct{}), make(chan struct{})
+	c.quitCh, c.doneCh = quitCh, doneCh
 
 	// start auto tickle
+	c.workers.Add(1)
 	go func() {
+		defer c.workers.Done()
+		defer close(doneCh)
+		defer ticker.Stop()
 		for {
 			select {
 			case <-ticker.C:
-				_ = c.dev.SetTickleMode(true, keepOld, posStart, posEnd, timeoutMsec)
-			case <-c.quitCh:
-				// quit when tickQuit is closed
-				ticker.Stop()
-				_ = c.dev.SetTickleMode(false, keepOld, 0, 0, 0)
-				return
+				_ = c.dev.SetTickleModeContext(ctx, true, keepOld, posStart, posEnd, timeoutMsec)
+				continue
+			case <-quitCh:
+				// quit when quitCh is closed
+			case <-ctx.Done():
+				// quit when the context is done
+			case <-c.closeCh:
+				// quit when the controller is closed
 			}
+			_ = c.dev.SetTickleMode(false, keepOld, 0, 0, 0)
+			return
 		}
 	}()
 	return nil
 }
 
-// StopAutoTickle stops the device from automatically tickling.
+// StopAutoTickle stops the device from automatically tickling, and waits for the auto tickle to quit. It does nothing if the auto tickle is not started.
 func (c *Controller) StopAutoTickle() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	c.stopAutoTickle()
+}
+
+// stopAutoTickle stops the auto tickle worker if it's started and waits for it to quit.
+func (c *Controller) stopAutoTickle() {
 	if c.quitCh != nil {
 		close(c.quitCh)
+		<-c.doneCh
+		c.quitCh, c.doneCh = nil, nil
 	}
 }
 
@@ -333,20 +458,26 @@ func (c *Controller) StopAutoTickle() {
 // The timeout should be at least 10ms, or it will be ignored by the firmware. An error will be returned for this case.
 // If keepOld is true, the current pattern will be kept playing, otherwise it will be stopped.
 func (c *Controller) SimpleTickle(posStart, posEnd uint, timeout time.Duration, keepOld bool) error {
+	return c.SimpleTickleContext(context.Background(), posStart, posEnd, timeout, keepOld)
+}
+
+// SimpleTickleContext works like SimpleTickle with the given context.
+func (c *Controller) SimpleTickleContext(ctx context.Context, posStart, posEnd uint, timeout time.Duration, keepOld bool) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.settleCoalesced()
 
 	// ensure start < end and end < max
 	if !c.isPosRangeValid(posStart, posEnd) {
-		return errInvalidPosition
+		return ErrInvalidPosition
 	}
 	if timeout < minTimeDur {
-		return errInvalidTimeout
+		return ErrInvalidTimeout
 	}
 	timeoutMsec := uint(timeout.Milliseconds())
 
 	// tickle once
-	return c.dev.SetTickleMode(true, keepOld, posStart, posEnd, timeoutMsec)
+	return c.dev.SetTickleModeContext(ctx, true, keepOld, posStart, posEnd, timeoutMsec)
 }
 
 // StartManualTickle sets the device to tickle manually.
@@ -354,17 +485,27 @@ func (c *Controller) SimpleTickle(posStart, posEnd uint, timeout time.Duration,
 // Signals should be sent to the returned channel to tickle before the timeout, otherwise the given pattern will be played.
 // If keepOld is true, the current pattern will be kept playing, otherwise it will be stopped.
 //
-// To stop the manual tickle, close the returned channel.
+// To stop the manual tickle, close the returned channel. It's also stopped when the controller is closed, and signals sent after that are discarded until the channel is closed.
 func (c *Controller) StartManualTickle(posStart, posEnd uint, timeout time.Duration, keepOld bool) (chan<- struct{}, error) {
+	return c.StartManualTickleContext(context.Background(), posStart, posEnd, timeout, keepOld)
+}
+
+// StartManualTickleContext works like StartManualTickle with the given context, the manual tickle also stops when the context is done.
+// Signals sent after that are discarded until the channel is closed, so senders are never blocked.
+func (c *Controller) StartManualTickleContext(ctx context.Context, posStart, posEnd uint, timeout time.Duration, keepOld bool) (chan<- struct{}, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.settleCoalesced()
 
 	// ensure start < end and end < max
 	if !c.isPosRangeValid(posStart, posEnd) {
-		return nil, errInvalidPosition
+		return nil, ErrInvalidPosition
 	}
 	if timeout < minTimeDur {
-		return nil, errInvalidTimeout
+		return nil, ErrInvalidTimeout
+	}
+	if c.closed {
+		return nil, ErrClosed
 	}
 
 	// prepare manual ticker
@@ -372,19 +513,98 @@ func (c *Controller) StartManualTickle(posStart, posEnd uint, timeout time.Durat
 	timeoutMsec := uint(timeout.Milliseconds())
 
 	// start tickle
+	c.workers.Add(1)
 	go func() {
-		for range tickCh {
-			_ = c.dev.SetTickleMode(true, keepOld, posStart, posEnd, timeoutMsec)
+		defer c.workers.Done()
+		for {
+			select {
+			case _, ok := <-tickCh:
+				if ok {
+					_ = c.dev.SetTickleModeContext(ctx, true, keepOld, posStart, posEnd, timeoutMsec)
+					continue
+				}
+				// quit when tickCh is closed
+			case <-ctx.Done():
+				// quit when the context is done
+				go drainTicks(tickCh)
+			case <-c.closeCh:
+				// quit when the controller is closed
+				go drainTicks(tickCh)
+			}
+			_ = c.dev.SetTickleMode(false, keepOld, 0, 0, 0)
+			return
 		}
-		// quit when tickCh is closed
-		_ = c.dev.SetTickleMode(false, keepOld, 0, 0, 0)
 	}()
 	return tickCh, nil
 }
 
+// SetBootPattern sets the pattern loop to play automatically on power-up of the device, or disables it. It requires mk2 devices with firmware v204+ or mk3+ devices.
+// The pattern in RAM will be lost after the device is powered off, so call WritePattern() to save the pattern to the device's flash first.
+// The repeat parameter specifies how many times to repeat, 0 means infinite.
+func (c *Controller) SetBootPattern(posStart, posEnd, repeat uint, enabled bool) error {
+	return c.SetBootPatternContext(context.Background(), posStart, posEnd, repeat, enabled)
+}
+
+// SetBootPatternContext works like SetBootPattern with the given context.
+func (c *Controller) SetBootPatternContext(ctx context.Context, posStart, posEnd, repeat uint, enabled bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.settleCoalesced()
+
+	// ensure range and firmware are valid
+	if !c.isPosRangeValid(posStart, posEnd) {
+		return ErrInvalidPosition
+	}
+	if repeat > maxRepeat {
+		return ErrInvalidRepeatTimes
+	}
+	if err := c.requireCapability(ctx, "startup params", hasStartupParams); err != nil {
+		return err
+	}
+
+	return c.dev.SetStartupParamsContext(ctx, enabled, posStart, posEnd, repeat)
+}
+
+// GetBootPattern returns the pattern loop to play on power-up of the device without states, and whether it's enabled. It requires mk2 devices with firmware v204+ or mk3+ devices.
+func (c *Controller) GetBootPattern() (Pattern, bool, error) {
+	return c.GetBootPatternContext(context.Background())
+}
+
+// GetBootPatternContext works like GetBootPattern with the given context.
+func (c *Controller) GetBootPatternContext(ctx context.Context) (Pattern, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.settleCoalesced()
+
+	// ensure firmware is valid
+	if err := c.requireCapability(ctx, "startup params", hasStartupParams); err != nil {
+		return Pattern{}, false, err
+	}
+
+	sp, err := c.dev.ReadStartupParamsContext(ctx)
+	if err != nil {
+		return Pattern{}, false, err
+	}
+	return Pattern{
+		StartPosition: sp.LoopStartPos,
+		EndPosition:   sp.LoopEndPos,
+		RepeatTimes:   sp.RepeatTimes,
+	}, sp.Enabled, nil
+}
+
 // isPosRangeValid checks if the given position range is valid.
 func (c *Controller) isPosRangeValid(start, end uint) bool {
 	// check pattern to ensure start <= end and end < max, 0 is a special case equals to last position
 	mp := getMaxPattern(c.dev.gen)
 	return (start <= end && end < mp) || (start < mp && end == 0)
 }
+
+// hasPerLEDAddressing returns true if the capabilities include per-LED addressing.
+func hasPerLEDAddressing(cp Capabilities) bool {
+	return cp.PerLEDAddressing
+}
+
+// hasStartupParams returns true if the capabilities include startup params.
+func hasStartupParams(cp Capabilities) bool {
+	return cp.StartupParams
+}
@@ -0,0 +1,281 @@
+package blink1_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	b1 "github.com/b1ug/blink1-go"
+)
+
+func TestController_BootPattern(t *testing.T) {
+	emu := b1.NewEmulator(2, "EMU00001")
+	c, err := b1.NewControllerWithTransport(emu)
+	if err != nil {
+		t.Fatalf("NewControllerWithTransport() got unexpected error: %v", err)
+	}
+	defer c.Close()
+	c.SetGammaCorrection(false)
+
+	// save pattern and enable it on boot
+	st := b1.NewLightState(b1.ColorBlue, 0, b1.LEDAll)
+	if err := c.LoadPattern(0, 1, b1.StateSequence{st, st}); err != nil {
+		t.Fatalf("LoadPattern() got unexpected error: %v", err)
+	}
+	if err := c.WritePattern(); err != nil {
+		t.Fatalf("WritePattern() got unexpected error: %v", err)
+	}
+	if err := c.SetBootPattern(0, 1, 0, true); err != nil {
+		t.Fatalf("SetBootPattern() got unexpected error: %v", err)
+	}
+	if err := c.SetBootPattern(2, 1, 0, true); !errors.Is(err, b1.ErrInvalidPosition) {
+		t.Errorf("SetBootPattern() with invalid range got error: %v, want ErrInvalidPosition", err)
+	}
+	pt, enabled, err := c.GetBootPattern()
+	if err != nil {
+		t.Fatalf("GetBootPattern() got unexpected error: %v", err)
+	}
+	if !enabled || pt.StartPosition != 0 || pt.EndPosition != 1 || pt.RepeatTimes != 0 {
+		t.Errorf("GetBootPattern() = %v, %v, want enabled loop [0,1]", pt, enabled)
+	}
+
+	// power cycle
+	emu.PowerCycle()
+	time.Sleep(20 * time.Millisecond)
+	if ok, err := c.IsPatternPlaying(); err != nil || !ok {
+		t.Errorf("IsPatternPlaying() after power cycle = %v, %v, want true", ok, err)
+	}
+	if cl, _ := c.ReadColor(b1.LED1); b1.ColorToHex(cl) != "#0000FF" {
+		t.Errorf("ReadColor() after power cycle = %v, want #0000FF", cl)
+	}
+
+	// disable it on boot
+	if err := c.SetBootPattern(0, 1, 0, false); err != nil {
+		t.Fatalf("SetBootPattern() got unexpected error: %v", err)
+	}
+	emu.PowerCycle()
+	if ok, err := c.IsPatternPlaying(); err != nil || ok {
+		t.Errorf("IsPatternPlaying() after power cycle = %v, %v, want false", ok, err)
+	}
+}
+
+func TestController_BootPatternUnsupported(t *testing.T) {
+	emu := b1.NewEmulator(2, "EMU00002")
+	emu.SetFirmwareVersion(2, 3)
+	for _, tr := range []b1.Transport{emu, b1.NewEmulator(1, "EMU00003")} {
+		c, err := b1.NewControllerWithTransport(tr)
+		if err != nil {
+			t.Fatalf("NewControllerWithTransport() got unexpected error: %v", err)
+		}
+		var ue *b1.UnsupportedError
+		if err := c.SetBootPattern(0, 1, 0, true); !errors.As(err, &ue) || ue.Feature != "startup params" {
+			t.Errorf("SetBootPattern() on %v got %v, want UnsupportedError", c, err)
+		}
+		if _, _, err := c.GetBootPattern(); !errors.Is(err, b1.ErrUnsupported) {
+			t.Errorf("GetBootPattern() on %v got %v, want ErrUnsupported", c, err)
+		}
+		c.Close()
+	}
+}
+
+func TestController_Capabilities(t *testing.T) {
+	tests := []struct {
+		gen          uint16
+		major, minor byte
+		want         b1.Capabilities
+	}{
+		{1, 1, 5, b1.Capabilities{PatternLines: 12}},
+		{2, 2, 3, b1.Capabilities{PerLEDAddressing: true, SetRGBNowLEDBug: true, PatternLines: 32}},
+		{2, 2, 4, b1.Capabilities{PerLEDAddressing: true, StartupParams: true, SetRGBNowLEDBug: true, PatternLines: 32}},
+		{3, 3, 4, b1.Capabilities{PerLEDAddressing: true, StartupParams: true, Notes: true, PatternLines: 32}},
+	}
+	for _, tt := range tests {
+		emu := b1.NewEmulator(tt.gen, "EMU00001")
+		emu.SetFirmwareVersion(tt.major, tt.minor)
+		c, err := b1.NewControllerWithTransport(emu)
+		if err != nil {
+			t.Fatalf("NewControllerWithTransport() got unexpected error: %v", err)
+		}
+		fi, err := c.GetFirmwareInfo()
+		if err != nil {
+			t.Fatalf("GetFirmwareInfo() got unexpected error: %v", err)
+		}
+		if fi.Generation != tt.gen || fi.Major != uint(tt.major) || fi.Minor != uint(tt.minor) || string(fi.Raw) != string([]byte{'0' + tt.major, '0' + tt.minor}) {
+			t.Errorf("GetFirmwareInfo() = %+v, want mk%d v%d%d", fi, tt.gen, tt.major, tt.minor)
+		}
+		if cp, err := c.GetCapabilities(); err != nil || cp != tt.want {
+			t.Errorf("GetCapabilities() of %v = %v, %v, want %v", fi, cp, err, tt.want)
+		}
+		c.Close()
+	}
+}
+
+func TestController_SetRGBNowLEDBug(t *testing.T) {
+	for _, tt := range []struct {
+		gen  uint16
+		bug  bool
+		want string // LED2 after SetRGBNow(LED1)
+	}{
+		{2, true, "#FFFFFF"},
+		{3, false, "#FF0000"},
+	} {
+		gen := tt.gen
+		c, err := b1.NewControllerWithTransport(b1.NewEmulator(gen, "EMU00001"))
+		if err != nil {
+			t.Fatalf("NewControllerWithTransport() got unexpected error: %v", err)
+		}
+		if cp, err := c.GetCapabilities(); err != nil || cp.SetRGBNowLEDBug != tt.bug {
+			t.Errorf("GetCapabilities() of mk%d = %v, %v, want rgb_now_bug=%t", gen, cp, err, tt.bug)
+		}
+		if err := c.PlayColor(b1.ColorBlue); err != nil {
+			t.Fatalf("PlayColor() got unexpected error: %v", err)
+		}
+
+		// a single LED is faded in 0ms without turning the other one white
+		var last byte
+		c.SetObserver(b1.ObserverFunc(func(ev b1.CommandEvent) {
+			last = ev.Cmd
+		}))
+		if err := c.PlayState(b1.NewLightState(b1.ColorRed, 0, b1.LED2)); err != nil {
+			t.Fatalf("PlayState() on mk%d got unexpected error: %v", gen, err)
+		}
+		if last != 'c' {
+			t.Errorf("PlayState() on mk%d sent command %q, want 'c'", gen, last)
+		}
+		if cl, _ := c.ReadColor(b1.LED1); b1.ColorToHex(cl) != "#0000FF" {
+			t.Errorf("ReadColor(LED1) on mk%d = %v, want #0000FF", gen, b1.ColorToHex(cl))
+		}
+		if cl, _ := c.ReadColor(b1.LED2); b1.ColorToHex(cl) != "#FF0000" {
+			t.Errorf("ReadColor(LED2) on mk%d = %v, want #FF0000", gen, b1.ColorToHex(cl))
+		}
+
+		// the firmware bug itself, only on mk2
+		if err := c.GetDevice().SetRGBNow(0, 0xff, 0, b1.LED1); err != nil {
+			t.Fatalf("SetRGBNow() on mk%d got unexpected error: %v", gen, err)
+		}
+		if cl, _ := c.ReadColor(b1.LED2); b1.ColorToHex(cl) != tt.want {
+			t.Errorf("ReadColor(LED2) after SetRGBNow(LED1) on mk%d = %v, want %s", gen, b1.ColorToHex(cl), tt.want)
+		}
+		c.Close()
+	}
+}
+
+func TestController_PerLEDUnsupported(t *testing.T) {
+	c, err := b1.NewControllerWithTransport(b1.NewEmulator(1, "EMU00001"))
+	if err != nil {
+		t.Fatalf("NewControllerWithTransport() got unexpected error: %v", err)
+	}
+	defer c.Close()
+
+	var ue *b1.UnsupportedError
+	if err := c.PlayState(b1.NewLightState(b1.ColorRed, 0, b1.LED2)); !errors.As(err, &ue) {
+		t.Errorf("PlayState() with LED2 on mk1 got %v, want UnsupportedError", err)
+	} else if want := "b1: per-LED addressing is unsupported on mk1 firmware v105"; ue.Error() != want {
+		t.Errorf("UnsupportedError.Error() = %q, want %q", ue.Error(), want)
+	}
+	if err := c.LoadPattern(0, 1, b1.StateSequence{b1.NewLightState(b1.ColorRed, 0, b1.LED1)}); !errors.As(err, &ue) {
+		t.Errorf("LoadPattern() with LED1 on mk1 got %v, want UnsupportedError", err)
+	}
+	if err := c.PlayState(b1.NewLightState(b1.ColorRed, 0, b1.LEDAll)); err != nil {
+		t.Errorf("PlayState() with all LEDs on mk1 got unexpected error: %v", err)
+	}
+}
+
+func TestController_Context(t *testing.T) {
+	c := newEmulatedController(t, 2)
+	defer c.Close()
+
+	// canceled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.PlayColorContext(ctx, b1.ColorRed); !errors.Is(err, context.Canceled) {
+		t.Errorf("PlayColorContext() with canceled context got error: %v, want context.Canceled", err)
+	}
+	if _, err := c.ReadColorContext(ctx, b1.LED1); !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadColorContext() with canceled context got error: %v, want context.Canceled", err)
+	}
+	if _, err := c.GetDevice().GetVersionContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetVersionContext() with canceled context got error: %v, want context.Canceled", err)
+	}
+
+	// infinite pattern blocks until deadline
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	st := b1.NewLightState(b1.ColorGreen, 50*time.Millisecond, b1.LEDAll)
+	pt := b1.Pattern{StartPosition: 0, EndPosition: 1, RepeatTimes: 0, Sequence: b1.StateSequence{st, st}}
+	start := time.Now()
+	if err := c.PlayPatternBlockingContext(ctx, pt); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("PlayPatternBlockingContext() got error: %v, want context.DeadlineExceeded", err)
+	}
+	if el := time.Since(start); el > time.Second {
+		t.Errorf("PlayPatternBlockingContext() returned after %v, want around 100ms", el)
+	}
+
+	// background context still works
+	if err := c.PlayColor(b1.ColorBlue); err != nil {
+		t.Errorf("PlayColor() got unexpected error: %v", err)
+	}
+}
+
+func TestController_Lifecycle(t *testing.T) {
+	emu := b1.NewEmulator(2, "EMU00001")
+	c, err := b1.NewControllerWithTransport(emu)
+	if err != nil {
+		t.Fatalf("NewControllerWithTransport() got unexpected error: %v", err)
+	}
+
+	// start and stop auto tickle idempotently
+	c.StopAutoTickle()
+	if err := c.StartAutoTickle(0, 1, false); err != nil {
+		t.Fatalf("StartAutoTickle() got unexpected error: %v", err)
+	}
+	if err := c.StartAutoTickle(0, 1, false); err != nil {
+		t.Fatalf("StartAutoTickle() again got unexpected error: %v", err)
+	}
+	c.StopAutoTickle()
+	c.StopAutoTickle()
+
+	// close stops the workers
+	tickCh, err := c.StartManualTickle(0, 1, 100*time.Millisecond, false)
+	if err != nil {
+		t.Fatalf("StartManualTickle() got unexpected error: %v", err)
+	}
+	tickCh <- struct{}{}
+	if err := c.StartAutoTickle(0, 1, false); err != nil {
+		t.Fatalf("StartAutoTickle() got unexpected error: %v", err)
+	}
+	c.Close()
+	c.Close()
+
+	time.Sleep(150 * time.Millisecond)
+	d, err := b1.NewDevice(emu)
+	if err != nil {
+		t.Fatalf("NewDevice() got unexpected error: %v", err)
+	}
+	if st, err := d.ReadPlaystate(); err != nil || st.IsPlaying {
+		t.Errorf("ReadPlaystate() after Close = %v, %v, want tickle stopped", st, err)
+	}
+
+	// signals after close are discarded, not blocked
+	select {
+	case tickCh <- struct{}{}:
+	case <-time.After(time.Second):
+		t.Errorf("tick after Close got blocked")
+	}
+	close(tickCh)
+
+	// operations after close
+	if err := c.PlayColor(b1.ColorRed); !errors.Is(err, b1.ErrClosed) {
+		t.Errorf("PlayColor() after Close got error: %v, want ErrClosed", err)
+	}
+	if err := c.StartAutoTickle(0, 1, false); !errors.Is(err, b1.ErrClosed) {
+		t.Errorf("StartAutoTickle() after Close got error: %v, want ErrClosed", err)
+	}
+	if _, err := c.StartManualTickle(0, 1, time.Second, false); !errors.Is(err, b1.ErrClosed) {
+		t.Errorf("StartManualTickle() after Close got error: %v, want ErrClosed", err)
+	}
+	if _, err := c.GetDevice().GetVersion(); !errors.Is(err, b1.ErrClosed) {
+		t.Errorf("GetVersion() after Close got error: %v, want ErrClosed", err)
+	}
+}
@@ -1,7 +1,7 @@
 package blink1
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -11,9 +11,8 @@ import (
 
 var (
 	// common errors
-	errNilDeviceInfo  = errors.New("b1: nil device info")
-	errNotBlink1      = errors.New("b1: device is not blink(1)")
-	errDeviceNotFound = fmt.Errorf("b1: device not found")
+	errNilDeviceInfo = fmt.Errorf("%w: nil device info", ErrInvalidArgument)
+	errNilTransport  = fmt.Errorf("%w: nil transport", ErrInvalidArgument)
 )
 
 // Device represents a blink(1) device and provides low-level APIs using HID commands for direct control.
@@ -24,9 +23,20 @@ type Device struct {
 	sn  string // serial number
 
 	// state
-	mu   sync.Mutex // mutex lock, only for atomic operations like I/O & close
-	info *hid.DeviceInfo
-	dev  hid.Device
+	mu     sync.Mutex // mutex lock, only for atomic operations like I/O & close
+	info   *hid.DeviceInfo
+	dev    Transport
+	closed bool
+	lock   *DeviceLock // nil if the device is not locked
+
+	// instrumentation
+	observer   Observer // nil if not observed
+	logger     Logger   // nil if not logged
+	reconnects uint     // number of reconnections, for telling if an exchange is retried after reconnecting
+
+	// resilient mode
+	reopen func() (Transport, error) // nil if disabled
+	replay *deviceReplay             // last known state to replay after reconnecting
 }
 
 // OpenDevice opens a blink(1) device which is connected to the system.
@@ -36,24 +46,47 @@ func OpenDevice(info *hid.DeviceInfo) (*Device, error) {
 		return nil, errNilDeviceInfo
 	}
 	if !IsBlink1Device(info) {
-		return nil, errNotBlink1
+		return nil, ErrNotBlink1
 	}
 
 	// open device
-	dev, err := info.Open()
+	tr, err := openHIDTransport(info)
 	if err != nil {
 		return nil, err
 	}
 
 	// instance
-	b1 := &Device{
+	return newDevice(info, tr), nil
+}
+
+// NewDevice creates a blink(1) device for the given transport, which can be backed by any HID backend.
+// The device info returned by the transport is used to identify and verify the device.
+func NewDevice(tr Transport) (*Device, error) {
+	// verify transport and device
+	if tr == nil {
+		return nil, errNilTransport
+	}
+	info := tr.GetDeviceInfo()
+	if info == nil {
+		return nil, errNilDeviceInfo
+	}
+	if !IsBlink1Device(info) {
+		return nil, ErrNotBlink1
+	}
+
+	// instance
+	return newDevice(info, tr), nil
+}
+
+// newDevice creates a device instance with the given device info and opened transport.
+func newDevice(info *hid.DeviceInfo, tr Transport) *Device {
+	return &Device{
 		pn:   info.Product,
 		gen:  info.VersionNumber,
 		sn:   info.SerialNumber,
 		info: info,
-		dev:  dev,
+		dev:  tr,
 	}
-	return b1, nil
 }
 
 func (b1 *Device) String() string {
@@ -80,74 +113,80 @@ func (b1 *Device) GetSerialNumber() string {
 	return b1.sn
 }
 
-// Close closes the device and release the kept resources.
+// Close closes the device and release the kept resources. It's safe to call Close multiple times, and operations after Close will return ErrClosed.
 func (b1 *Device) Close() {
 	b1.mu.Lock()
 	defer b1.mu.Unlock()
-	if b1.dev != nil {
+	if !b1.closed {
+		b1.closed = true
 		b1.dev.Close()
-		b1.dev = nil
+		if b1.lock != nil {
+			_ = b1.lock.Unlock()
+		}
 	}
 }
 
 // write sends the specified buffer as feature report to the device.
-func (b1 *Device) write(buf []byte) error {
+func (b1 *Device) write(ctx context.Context, buf []byte) error {
 	b1.mu.Lock()
 	defer b1.mu.Unlock()
 
 	// send feature report
-	if err := b1.dev.WriteFeature(buf); err != nil {
-		return fmt.Errorf("b1: write fail: %w", err)
+	if err := b1.exchange(ctx, "write", nil, func(tr Transport) error {
+		return tr.WriteFeature(buf)
+	}, buf); err != nil {
+		return err
 	}
+	b1.track(buf)
 	return nil
 }
 
 // doubleWrite works like write but sends the specified buffers one by one.
-func (b1 *Device) doubleWrite(buf1, buf2 []byte) error {
+func (b1 *Device) doubleWrite(ctx context.Context, buf1, buf2 []byte) error {
 	b1.mu.Lock()
 	defer b1.mu.Unlock()
 
 	// send feature reports
-	if err := b1.dev.WriteFeature(buf1); err != nil {
-		return fmt.Errorf("b1: write buf1 fail: %w", err)
-	}
-	if err := b1.dev.WriteFeature(buf2); err != nil {
-		return fmt.Errorf("b1: write buf2 fail: %w", err)
+	if err := b1.exchange(ctx, "write", nil, func(tr Transport) error {
+		if err := tr.WriteFeature(buf1); err != nil {
+			return fmt.Errorf("buf1: %w", err)
+		}
+		if err := tr.WriteFeature(buf2); err != nil {
+			return fmt.Errorf("buf2: %w", err)
+		}
+		return nil
+	}, buf1, buf2); err != nil {
+		return err
 	}
+	b1.track(buf1, buf2)
 	return nil
 }
 
 // read sends the feature report to the device and gets the response and writes it to the specified buffer.
-func (b1 *Device) read(buf []byte) error {
-	b1.mu.Lock()
-	defer b1.mu.Unlock()
-
-	// send feature report
-	_ = b1.dev.WriteFeature(buf)
-
-	// get feature report
-	if _, err := b1.dev.ReadFeature(buf); err != nil {
-		return fmt.Errorf("b1: read fail: %w", err)
-	}
-	return nil
+func (b1 *Device) read(ctx context.Context, buf []byte) error {
+	return b1.delayRead(ctx, buf, 0)
 }
 
 // delayWrite works like read but waits for the specified milliseconds before reading the response.
-func (b1 *Device) delayRead(buf []byte, delayMs int) error {
+func (b1 *Device) delayRead(ctx context.Context, buf []byte, delayMs int) error {
 	b1.mu.Lock()
 	defer b1.mu.Unlock()
 
-	// send feature report
-	_ = b1.dev.WriteFeature(buf)
-
-	// wait
-	if delayMs > 0 {
-		time.Sleep(time.Duration(delayMs) * time.Millisecond)
-	}
-
-	// get feature report
-	if _, err := b1.dev.ReadFeature(buf); err != nil {
-		return fmt.Errorf("b1: read fail: %w", err)
-	}
-	return nil
+	cmd := append([]byte(nil), buf...)
+	return b1.exchange(ctx, "read", buf, func(tr Transport) error {
+		// send feature report
+		copy(buf, cmd)
+		_ = tr.WriteFeature(buf)
+
+		// wait
+		if delayMs > 0 {
+			if err := sleepContext(ctx, time.Duration(delayMs)*time.Millisecond); err != nil {
+				return err
+			}
+		}
+
+		// get feature report
+		_, err := tr.ReadFeature(buf)
+		return err
+	}, cmd)
 }
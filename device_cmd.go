@@ -1,6 +1,9 @@
 package blink1
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // FadeToRGB fades the given LED to the specified RGB color over the specified time.
 //
@@ -9,6 +12,11 @@ import "fmt"
 //
 // Returns an error if there was a problem communicating with the device.
 func (b1 *Device) FadeToRGB(r, g, b byte, fadeMsec uint, ledN LEDIndex) error {
+	return b1.FadeToRGBContext(context.Background(), r, g, b, fadeMsec, ledN)
+}
+
+// FadeToRGBContext works like FadeToRGB with the given context.
+func (b1 *Device) FadeToRGBContext(ctx context.Context, r, g, b byte, fadeMsec uint, ledN LEDIndex) error {
 	// command data
 	buf := make([]byte, cmdBufSize)
 	buf[0] = reportID
@@ -18,16 +26,21 @@ func (b1 *Device) FadeToRGB(r, g, b byte, fadeMsec uint, ledN LEDIndex) error {
 	buf[7] = ledN.ToByte()
 
 	// execute
-	return b1.write(buf)
+	return b1.write(ctx, buf)
 }
 
 // SetRGBNow sets the given LED to the specified RGB color immediately.
 //
 // The ledN parameter specifies which LED to control: 0=all, 1=top LED, 2=bottom LED.
-// For mk2+ devices, ledN > 0 will set all LEDs to the white color (255, 255, 255) and ignore the RGB values due to a firmware bug.
+// For mk2 devices, ledN > 0 will set all LEDs to the white color (255, 255, 255) and ignore the RGB values due to a firmware bug.
 //
 // Returns an error if there was a problem communicating with the device.
 func (b1 *Device) SetRGBNow(r, g, b byte, ledN LEDIndex) error {
+	return b1.SetRGBNowContext(context.Background(), r, g, b, ledN)
+}
+
+// SetRGBNowContext works like SetRGBNow with the given context.
+func (b1 *Device) SetRGBNowContext(ctx context.Context, r, g, b byte, ledN LEDIndex) error {
 	// command data
 	buf := make([]byte, cmdBufSize)
 	buf[0] = reportID
@@ -36,7 +49,7 @@ func (b1 *Device) SetRGBNow(r, g, b byte, ledN LEDIndex) error {
 	buf[7] = ledN.ToByte()
 
 	// execute
-	return b1.write(buf)
+	return b1.write(ctx, buf)
 }
 
 // ReadRGB reads the current RGB color of the specified LED.
@@ -46,6 +59,11 @@ func (b1 *Device) SetRGBNow(r, g, b byte, ledN LEDIndex) error {
 //
 // Returns the RGB values or an error if there was a problem communicating with the device.
 func (b1 *Device) ReadRGB(ledN LEDIndex) (r, g, b byte, err error) {
+	return b1.ReadRGBContext(context.Background(), ledN)
+}
+
+// ReadRGBContext works like ReadRGB with the given context.
+func (b1 *Device) ReadRGBContext(ctx context.Context, ledN LEDIndex) (r, g, b byte, err error) {
 	// command data
 	buf := make([]byte, cmdBufSize)
 	buf[0] = reportID
@@ -53,7 +71,7 @@ func (b1 *Device) ReadRGB(ledN LEDIndex) (r, g, b byte, err error) {
 	buf[7] = ledN.ToByte()
 
 	// execute
-	if err = b1.read(buf); err != nil {
+	if err = b1.read(ctx, buf); err != nil {
 		return
 	}
 
@@ -71,6 +89,11 @@ func (b1 *Device) ReadRGB(ledN LEDIndex) (r, g, b byte, err error) {
 //
 // Returns an error if the arguments are invalid or there was a problem communicating with the device.
 func (b1 *Device) PlayLoop(play bool, posStart, posEnd, times uint) error {
+	return b1.PlayLoopContext(context.Background(), play, posStart, posEnd, times)
+}
+
+// PlayLoopContext works like PlayLoop with the given context.
+func (b1 *Device) PlayLoopContext(ctx context.Context, play bool, posStart, posEnd, times uint) error {
 	// validate positions
 	if err := b1.checkPatternPos(posStart); err != nil {
 		return err
@@ -92,7 +115,7 @@ func (b1 *Device) PlayLoop(play bool, posStart, posEnd, times uint) error {
 	buf[5] = byte(times & 0xff)
 
 	// execute
-	return b1.write(buf)
+	return b1.write(ctx, buf)
 }
 
 // ReadPlaystate reads the current playing state of the pattern loop.
@@ -100,13 +123,18 @@ func (b1 *Device) PlayLoop(play bool, posStart, posEnd, times uint) error {
 // Returns the DevicePatternState struct containing the loop state and the current position,
 // or an error if there was a problem communicating with the device.
 func (b1 *Device) ReadPlaystate() (st DevicePatternState, err error) {
+	return b1.ReadPlaystateContext(context.Background())
+}
+
+// ReadPlaystateContext works like ReadPlaystate with the given context.
+func (b1 *Device) ReadPlaystateContext(ctx context.Context) (st DevicePatternState, err error) {
 	// command data
 	buf := make([]byte, cmdBufSize)
 	buf[0] = reportID
 	buf[1] = 'S'
 
 	// execute
-	if err := b1.read(buf); err != nil {
+	if err := b1.read(ctx, buf); err != nil {
 		return st, err
 	}
 
@@ -127,6 +155,11 @@ func (b1 *Device) ReadPlaystate() (st DevicePatternState, err error) {
 //
 // Returns an error if the arguments are invalid or there was a problem communicating with the device.
 func (b1 *Device) SetPatternLine(pos uint, st DeviceLightState) error {
+	return b1.SetPatternLineContext(context.Background(), pos, st)
+}
+
+// SetPatternLineContext works like SetPatternLine with the given context.
+func (b1 *Device) SetPatternLineContext(ctx context.Context, pos uint, st DeviceLightState) error {
 	// validate position
 	if err := b1.checkPatternPos(pos); err != nil {
 		return err
@@ -147,11 +180,11 @@ func (b1 *Device) SetPatternLine(pos uint, st DeviceLightState) error {
 		buf1[1] = 'l'
 		buf1[2] = st.LED.ToByte()
 		// execute
-		return b1.doubleWrite(buf1, buf2)
+		return b1.doubleWrite(ctx, buf1, buf2)
 	}
 
 	// execute for mk1
-	return b1.write(buf2)
+	return b1.write(ctx, buf2)
 }
 
 // ReadPatternLine reads the specified pattern line.
@@ -161,6 +194,11 @@ func (b1 *Device) SetPatternLine(pos uint, st DeviceLightState) error {
 // Returns the DeviceLightState struct containing the RGB values and the LEDType, or an error if there was invalid pattern position or
 // a problem communicating with the device.
 func (b1 *Device) ReadPatternLine(pos uint) (st DeviceLightState, err error) {
+	return b1.ReadPatternLineContext(context.Background(), pos)
+}
+
+// ReadPatternLineContext works like ReadPatternLine with the given context.
+func (b1 *Device) ReadPatternLineContext(ctx context.Context, pos uint) (st DeviceLightState, err error) {
 	// validate position
 	if err = b1.checkPatternPos(pos); err != nil {
 		return
@@ -173,7 +211,7 @@ func (b1 *Device) ReadPatternLine(pos uint) (st DeviceLightState, err error) {
 	buf[7] = byte(pos)
 
 	// execute
-	if err := b1.read(buf); err != nil {
+	if err := b1.read(ctx, buf); err != nil {
 		return st, err
 	}
 
@@ -188,6 +226,11 @@ func (b1 *Device) ReadPatternLine(pos uint) (st DeviceLightState, err error) {
 //
 // Returns an error if there was a problem communicating with the device.
 func (b1 *Device) SavePattern() error {
+	return b1.SavePatternContext(context.Background())
+}
+
+// SavePatternContext works like SavePattern with the given context.
+func (b1 *Device) SavePatternContext(ctx context.Context) error {
 	// command data
 	buf := make([]byte, cmdBufSize)
 	buf[0] = reportID
@@ -198,7 +241,7 @@ func (b1 *Device) SavePattern() error {
 	buf[5] = 0xFE
 
 	// execute and will always return error, because of issue with flash programming timing out USB
-	_ = b1.write(buf)
+	_ = b1.write(ctx, buf)
 	return nil
 }
 
@@ -211,6 +254,11 @@ func (b1 *Device) SavePattern() error {
 //
 // Returns an error if there was a problem communicating with the device.
 func (b1 *Device) SetTickleMode(play, keep bool, posStart, posEnd, timeoutMsec uint) error {
+	return b1.SetTickleModeContext(context.Background(), play, keep, posStart, posEnd, timeoutMsec)
+}
+
+// SetTickleModeContext works like SetTickleMode with the given context.
+func (b1 *Device) SetTickleModeContext(ctx context.Context, play, keep bool, posStart, posEnd, timeoutMsec uint) error {
 	// validate positions
 	if err := b1.checkPatternPos(posStart); err != nil {
 		return err
@@ -236,47 +284,358 @@ func (b1 *Device) SetTickleMode(play, keep bool, posStart, posEnd, timeoutMsec u
 	buf[6], buf[7] = byte(posStart), byte(posEnd)
 
 	// execute
-	return b1.write(buf)
+	return b1.write(ctx, buf)
 }
 
-// GetVersion returns the firmware version of the device.
+// SetStartupParams sets the startup (power-on) behavior of the device, it's only supported on mk2 devices with firmware v204+ and mk3+ devices.
+//
+// The enabled parameter specifies whether to play the pattern loop automatically on power-up.
+// The posStart and posEnd parameters specify the start and end positions of the loop, where positions should be [0, patt_max-1]. If posEnd is 0, it will be set to patt_max-1.
+// The times parameter specifies how many times to play the loop, 0 means infinite.
+//
+// Returns an error if the arguments are invalid or there was a problem communicating with the device.
+func (b1 *Device) SetStartupParams(enabled bool, posStart, posEnd, times uint) error {
+	return b1.SetStartupParamsContext(context.Background(), enabled, posStart, posEnd, times)
+}
+
+// SetStartupParamsContext works like SetStartupParams with the given context.
+func (b1 *Device) SetStartupParamsContext(ctx context.Context, enabled bool, posStart, posEnd, times uint) error {
+	// validate positions
+	if err := b1.checkPatternPos(posStart); err != nil {
+		return err
+	}
+	if err := b1.checkPatternPos(posEnd); err != nil {
+		return err
+	}
+	// set posEnd to patt_max-1 if posEnd == 0, the same as the play loop command
+	if posEnd == 0 {
+		posEnd = getMaxPattern(b1.gen) - 1
+	}
+
+	// command data
+	buf := make([]byte, cmdBufSize)
+	buf[0] = reportID
+	buf[1] = 'B'
+	buf[2] = convBoolToByte(enabled)
+	buf[3], buf[4] = byte(posStart), byte(posEnd)
+	buf[5] = byte(times & 0xff)
+
+	// execute
+	return b1.write(ctx, buf)
+}
+
+// ReadStartupParams reads the startup (power-on) behavior of the device, it's only supported on mk2 devices with firmware v204+ and mk3+ devices.
+//
+// Returns the DeviceStartupParams struct containing the boot mode and the loop to play, or an error if there was a problem communicating with the device.
+func (b1 *Device) ReadStartupParams() (sp DeviceStartupParams, err error) {
+	return b1.ReadStartupParamsContext(context.Background())
+}
+
+// ReadStartupParamsContext works like ReadStartupParams with the given context.
+func (b1 *Device) ReadStartupParamsContext(ctx context.Context) (sp DeviceStartupParams, err error) {
+	// command data
+	buf := make([]byte, cmdBufSize)
+	buf[0] = reportID
+	buf[1] = 'b'
+
+	// execute
+	if err = b1.read(ctx, buf); err != nil {
+		return
+	}
+
+	// parse result
+	sp.Enabled = convByteToBool(buf[2])
+	sp.LoopStartPos = uint(buf[3])
+	sp.LoopEndPos = uint(buf[4])
+	sp.RepeatTimes = uint(buf[5])
+	return sp, nil
+}
+
+// GetVersion returns the firmware version of the device, e.g. 204 for v204.
 //
 // Returns an error if there was a problem communicating with the device.
 func (b1 *Device) GetVersion() (ver int, err error) {
+	return b1.GetVersionContext(context.Background())
+}
+
+// GetVersionContext works like GetVersion with the given context.
+func (b1 *Device) GetVersionContext(ctx context.Context) (ver int, err error) {
+	fi, err := b1.GetFirmwareInfoContext(ctx)
+	if err != nil {
+		return
+	}
+	return fi.Version(), nil
+}
+
+// GetFirmwareInfo returns the firmware version of the device along with its generation.
+// Use Capabilities() of the returned FirmwareInfo to find out the features supported by the firmware.
+//
+// Returns an error if there was a problem communicating with the device.
+func (b1 *Device) GetFirmwareInfo() (fi FirmwareInfo, err error) {
+	return b1.GetFirmwareInfoContext(context.Background())
+}
+
+// GetFirmwareInfoContext works like GetFirmwareInfo with the given context.
+func (b1 *Device) GetFirmwareInfoContext(ctx context.Context) (fi FirmwareInfo, err error) {
 	// command data
 	buf := make([]byte, cmdBufSize)
 	buf[0] = reportID
 	buf[1] = 'v'
 
 	// execute
-	if err = b1.read(buf); err != nil {
+	if err = b1.read(ctx, buf); err != nil {
 		return
 	}
 
 	// parse result
-	ver = int((buf[3]-'0')*100) + int(buf[4]-'0')
-	return
+	fi.Major = uint(buf[3] - '0')
+	fi.Minor = uint(buf[4] - '0')
+	fi.Raw = []byte{buf[3], buf[4]}
+	fi.Generation = b1.gen
+	return fi, nil
 }
 
 // Test sends a test command to the device, and returns the response.
 //
 // Returns the response from the device, or an error if there was a problem communicating with the device.
 func (b1 *Device) Test() ([]byte, error) {
+	return b1.TestContext(context.Background())
+}
+
+// TestContext works like Test with the given context.
+func (b1 *Device) TestContext(ctx context.Context) ([]byte, error) {
 	// command data
 	buf := make([]byte, cmdBufSize)
 	buf[0] = reportID
 	buf[1] = '!'
 
 	// execute
-	err := b1.delayRead(buf, 50)
+	err := b1.delayRead(ctx, buf, 50)
 	return buf, err
 }
 
+// ReadEEPROM reads a byte from the EEPROM of the device at the specified address.
+//
+// The addr parameter specifies the address to read, where addr should be [0, eeprom_max).
+//
+// Returns the byte value, or an error if the address is invalid or there was a problem communicating with the device.
+func (b1 *Device) ReadEEPROM(addr uint) (val byte, err error) {
+	return b1.ReadEEPROMContext(context.Background(), addr)
+}
+
+// ReadEEPROMContext works like ReadEEPROM with the given context.
+func (b1 *Device) ReadEEPROMContext(ctx context.Context, addr uint) (val byte, err error) {
+	// validate address
+	if err = checkEEPROMAddr(addr); err != nil {
+		return
+	}
+
+	// command data
+	buf := make([]byte, cmdBufSize)
+	buf[0] = reportID
+	buf[1] = 'e'
+	buf[2] = byte(addr)
+
+	// execute
+	if err = b1.delayRead(ctx, buf, 50); err != nil {
+		return
+	}
+
+	// parse result
+	val = buf[3]
+	return
+}
+
+// WriteEEPROM writes a byte to the EEPROM of the device at the specified address.
+//
+// The addr parameter specifies the address to write, where addr should be [0, eeprom_max).
+// Be careful, overwriting the known fields like serial number may make the device unrecognizable, use ReadEEPROMLayout() to inspect them first.
+//
+// Returns an error if the address is invalid or there was a problem communicating with the device.
+func (b1 *Device) WriteEEPROM(addr uint, val byte) error {
+	return b1.WriteEEPROMContext(context.Background(), addr, val)
+}
+
+// WriteEEPROMContext works like WriteEEPROM with the given context.
+func (b1 *Device) WriteEEPROMContext(ctx context.Context, addr uint, val byte) error {
+	// validate address
+	if err := checkEEPROMAddr(addr); err != nil {
+		return err
+	}
+
+	// command data
+	buf := make([]byte, cmdBufSize)
+	buf[0] = reportID
+	buf[1] = 'E'
+	buf[2] = byte(addr)
+	buf[3] = val
+
+	// execute
+	return b1.write(ctx, buf)
+}
+
+// ReadEEPROMLayout reads the known fields of the EEPROM layout from the device, i.e. oscillator calibration, boot mode and serial number.
+//
+// Returns the DeviceEEPROMLayout struct, or an error if there was a problem communicating with the device.
+func (b1 *Device) ReadEEPROMLayout() (ly DeviceEEPROMLayout, err error) {
+	return b1.ReadEEPROMLayoutContext(context.Background())
+}
+
+// ReadEEPROMLayoutContext works like ReadEEPROMLayout with the given context.
+func (b1 *Device) ReadEEPROMLayoutContext(ctx context.Context) (ly DeviceEEPROMLayout, err error) {
+	var raw [eeAddrPattern]byte
+	for addr := range raw {
+		if raw[addr], err = b1.ReadEEPROMContext(ctx, uint(addr)); err != nil {
+			return
+		}
+	}
+
+	// parse result
+	ly.OscCal = raw[eeAddrOscCal]
+	ly.BootMode = raw[eeAddrBootMode]
+	copy(ly.SerialNumber[:], raw[eeAddrSerial:eeAddrSerial+4])
+	return ly, nil
+}
+
+// ReadNote reads the note of the specified ID from the device, it's only supported on mk3+ devices.
+//
+// The noteID parameter specifies which note to read, where noteID should be [0, note_max).
+//
+// Returns the 100-byte note data, or an error if the device is not mk3+, the note ID is invalid or there was a problem communicating with the device.
+func (b1 *Device) ReadNote(noteID uint) ([]byte, error) {
+	return b1.ReadNoteContext(context.Background(), noteID)
+}
+
+// ReadNoteContext works like ReadNote with the given context.
+func (b1 *Device) ReadNoteContext(ctx context.Context, noteID uint) ([]byte, error) {
+	// validate device and note ID
+	if err := b1.checkMk3("notes"); err != nil {
+		return nil, err
+	}
+	if err := checkNoteID(noteID); err != nil {
+		return nil, err
+	}
+
+	// read the note in chunks, since it's larger than a report
+	note := make([]byte, 0, noteSize)
+	for off := 0; off < noteSize; off += noteChunk {
+		// command data
+		buf := make([]byte, cmdBuf3Size)
+		buf[0] = report3ID
+		buf[1] = 'f'
+		buf[2] = byte(noteID)
+		buf[3] = byte(off)
+
+		// execute
+		if err := b1.delayRead(ctx, buf, 50); err != nil {
+			return nil, err
+		}
+
+		// parse result
+		note = append(note, buf[4:4+noteChunk]...)
+	}
+	return note, nil
+}
+
+// WriteNote writes the note of the specified ID to the device, it's only supported on mk3+ devices.
+//
+// The noteID parameter specifies which note to write, where noteID should be [0, note_max).
+// The data parameter specifies the note data, which will be truncated or padded with zeros to 100 bytes.
+//
+// Returns an error if the device is not mk3+, the note ID is invalid or there was a problem communicating with the device.
+func (b1 *Device) WriteNote(noteID uint, data []byte) error {
+	return b1.WriteNoteContext(context.Background(), noteID, data)
+}
+
+// WriteNoteContext works like WriteNote with the given context.
+func (b1 *Device) WriteNoteContext(ctx context.Context, noteID uint, data []byte) error {
+	// validate device and note ID
+	if err := b1.checkMk3("notes"); err != nil {
+		return err
+	}
+	if err := checkNoteID(noteID); err != nil {
+		return err
+	}
+
+	// write the note in chunks, since it's larger than a report
+	note := make([]byte, noteSize)
+	copy(note, data)
+	for off := 0; off < noteSize; off += noteChunk {
+		// command data
+		buf := make([]byte, cmdBuf3Size)
+		buf[0] = report3ID
+		buf[1] = 'F'
+		buf[2] = byte(noteID)
+		buf[3] = byte(off)
+		copy(buf[4:], note[off:off+noteChunk])
+
+		// execute
+		if err := b1.write(ctx, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadChipID reads the unique ID of the microcontroller chip on the device, it's only supported on mk3+ devices.
+//
+// Returns the 8-byte unique ID, or an error if the device is not mk3+ or there was a problem communicating with the device.
+func (b1 *Device) ReadChipID() ([]byte, error) {
+	return b1.ReadChipIDContext(context.Background())
+}
+
+// ReadChipIDContext works like ReadChipID with the given context.
+func (b1 *Device) ReadChipIDContext(ctx context.Context) ([]byte, error) {
+	// validate device
+	if err := b1.checkMk3("chip ID"); err != nil {
+		return nil, err
+	}
+
+	// command data
+	buf := make([]byte, cmdBuf3Size)
+	buf[0] = report3ID
+	buf[1] = 'U'
+
+	// execute
+	if err := b1.read(ctx, buf); err != nil {
+		return nil, err
+	}
+
+	// parse result
+	id := make([]byte, chipIDSize)
+	copy(id, buf[2:2+chipIDSize])
+	return id, nil
+}
+
+// checkMk3 checks if the device is mk3+, which supports the commands with report ID 2.
+func (b1 *Device) checkMk3(feature string) error {
+	if b1.gen < 3 {
+		return &UnsupportedError{Feature: feature, Firmware: FirmwareInfo{Generation: b1.gen}}
+	}
+	return nil
+}
+
+// checkNoteID checks if the given note ID is valid, i.e. [0, note_max).
+func checkNoteID(id uint) error {
+	if id >= maxNote {
+		return fmt.Errorf("%w: note id %d is out of range [0, %d)", ErrInvalidArgument, id, maxNote)
+	}
+	return nil
+}
+
+// checkEEPROMAddr checks if the given address is valid for the EEPROM, i.e. [0, eeprom_max).
+func checkEEPROMAddr(addr uint) error {
+	if addr >= maxEEPROM {
+		return fmt.Errorf("%w: eeprom address %d is out of range [0, %d)", ErrInvalidArgument, addr, maxEEPROM)
+	}
+	return nil
+}
+
 // checkPatternPos checks if the given position is valid for the device, i.e. [0, patt_max).
 // Actually, the device will not check the position value, but the arbitrary value will cause the device to play the pattern unexpectedly.
 func (b1 *Device) checkPatternPos(pos uint) error {
 	if maxPos := getMaxPattern(b1.gen); pos >= maxPos {
-		return fmt.Errorf("b1: pattern position %d is out of range [0, %d)", pos, maxPos)
+		return fmt.Errorf("%w: %d is out of range [0, %d)", ErrInvalidPosition, pos, maxPos)
 	}
 	return nil
 }
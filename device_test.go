@@ -0,0 +1,391 @@
+package blink1_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	b1 "github.com/b1ug/blink1-go"
+	hid "github.com/b1ug/gid"
+)
+
+// fakeTransport is a Transport which records the written feature reports and replies with the canned responses.
+type fakeTransport struct {
+	info    *hid.DeviceInfo
+	written [][]byte
+	replies map[byte][]byte // command -> response
+	closed  bool
+	failErr error
+}
+
+func newFakeTransport(gen uint16) *fakeTransport {
+	return &fakeTransport{
+		info: &hid.DeviceInfo{
+			VendorID:      0x27B8,
+			ProductID:     0x01ED,
+			VersionNumber: gen,
+			Product:       "blink(1) fake",
+			SerialNumber:  "FAKE0001",
+		},
+		replies: make(map[byte][]byte),
+	}
+}
+
+func (t *fakeTransport) WriteFeature(buf []byte) error {
+	if t.failErr != nil {
+		return t.failErr
+	}
+	t.written = append(t.written, append([]byte(nil), buf...))
+	return nil
+}
+
+func (t *fakeTransport) ReadFeature(buf []byte) (int, error) {
+	if t.failErr != nil {
+		return 0, t.failErr
+	}
+	if len(t.written) == 0 {
+		return 0, errors.New("nothing written")
+	}
+	last := t.written[len(t.written)-1]
+	n := copy(buf, last)
+	if rsp, ok := t.replies[last[1]]; ok {
+		n = copy(buf, rsp)
+	}
+	return n, nil
+}
+
+func (t *fakeTransport) Close() {
+	t.closed = true
+}
+
+func (t *fakeTransport) GetDeviceInfo() *hid.DeviceInfo {
+	return t.info
+}
+
+func TestNewDevice(t *testing.T) {
+	if _, err := b1.NewDevice(nil); !errors.Is(err, b1.ErrInvalidArgument) {
+		t.Errorf("NewDevice(nil) got error: %v, want ErrInvalidArgument", err)
+	}
+	if _, err := b1.OpenDevice(nil); !errors.Is(err, b1.ErrInvalidArgument) {
+		t.Errorf("OpenDevice(nil) got error: %v, want ErrInvalidArgument", err)
+	}
+
+	ft := newFakeTransport(2)
+	ft.info.VendorID = 0x1234
+	if _, err := b1.NewDevice(ft); err == nil {
+		t.Errorf("NewDevice() should fail for non-blink(1) device")
+	}
+
+	ft = newFakeTransport(2)
+	d, err := b1.NewDevice(ft)
+	if err != nil {
+		t.Fatalf("NewDevice() got unexpected error: %v", err)
+	}
+	if d.GetGeneration() != 2 || d.GetSerialNumber() != "FAKE0001" || d.GetDeviceInfo() != ft.info {
+		t.Errorf("NewDevice() got unexpected profile: %v", d)
+	}
+	d.Close()
+	if !ft.closed {
+		t.Errorf("Close() should close the transport")
+	}
+}
+
+func TestDevice_Commands(t *testing.T) {
+	ft := newFakeTransport(2)
+	d, err := b1.NewDevice(ft)
+	if err != nil {
+		t.Fatalf("NewDevice() got unexpected error: %v", err)
+	}
+	defer d.Close()
+
+	tests := []struct {
+		name string
+		op   func() error
+		want [][]byte
+	}{
+		{
+			name: "FadeToRGB",
+			op:   func() error { return d.FadeToRGB(0xff, 0x00, 0x80, 500, b1.LED1) },
+			want: [][]byte{{0x01, 'c', 0xff, 0x00, 0x80, 0x00, 0x32, 0x01, 0x00}},
+		},
+		{
+			name: "SetRGBNow",
+			op:   func() error { return d.SetRGBNow(0x01, 0x02, 0x03, b1.LEDAll) },
+			want: [][]byte{{0x01, 'n', 0x01, 0x02, 0x03, 0x00, 0x00, 0x00, 0x00}},
+		},
+		{
+			name: "PlayLoop",
+			op:   func() error { return d.PlayLoop(true, 1, 0, 3) },
+			want: [][]byte{{0x01, 'p', 0x01, 0x01, 0x1f, 0x03, 0x00, 0x00, 0x00}},
+		},
+		{
+			name: "SetPatternLine",
+			op: func() error {
+				return d.SetPatternLine(5, b1.DeviceLightState{R: 0x10, G: 0x20, B: 0x30, LED: b1.LED2, FadeTimeMsec: 1000})
+			},
+			want: [][]byte{
+				{0x01, 'l', 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+				{0x01, 'P', 0x10, 0x20, 0x30, 0x00, 0x64, 0x05, 0x00},
+			},
+		},
+		{
+			name: "SetTickleMode",
+			op:   func() error { return d.SetTickleMode(true, false, 2, 4, 3000) },
+			want: [][]byte{{0x01, 'D', 0x01, 0x01, 0x2c, 0x00, 0x02, 0x05, 0x00}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft.written = nil
+			if err := tt.op(); err != nil {
+				t.Fatalf("%s() got unexpected error: %v", tt.name, err)
+			}
+			if len(ft.written) != len(tt.want) {
+				t.Fatalf("%s() wrote %d reports, want %d", tt.name, len(ft.written), len(tt.want))
+			}
+			for i := range tt.want {
+				if !bytes.Equal(ft.written[i], tt.want[i]) {
+					t.Errorf("%s() report #%d = %x, want %x", tt.name, i, ft.written[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDevice_Read(t *testing.T) {
+	ft := newFakeTransport(2)
+	ft.replies['v'] = []byte{0x01, 'v', 0x00, '2', '4', 0x00, 0x00, 0x00, 0x00}
+	ft.replies['r'] = []byte{0x01, 'r', 0x11, 0x22, 0x33, 0x00, 0x00, 0x01, 0x00}
+	d, err := b1.NewDevice(ft)
+	if err != nil {
+		t.Fatalf("NewDevice() got unexpected error: %v", err)
+	}
+	defer d.Close()
+
+	if ver, err := d.GetVersion(); err != nil || ver != 204 {
+		t.Errorf("GetVersion() = %d, %v, want 204", ver, err)
+	}
+	if r, g, b, err := d.ReadRGB(b1.LED1); err != nil || r != 0x11 || g != 0x22 || b != 0x33 {
+		t.Errorf("ReadRGB() = %x %x %x, %v, want 11 22 33", r, g, b, err)
+	}
+
+	ft.failErr = errors.New("unplugged")
+	if _, err := d.GetVersion(); err == nil {
+		t.Errorf("GetVersion() should fail with broken transport")
+	}
+}
+
+func TestDevice_Mk3(t *testing.T) {
+	// mk2 doesn't support mk3 commands
+	d2, _ := b1.NewDevice(b1.NewEmulator(2, "EMU00002"))
+	var ue *b1.UnsupportedError
+	if _, err := d2.ReadNote(0); !errors.As(err, &ue) || ue.Feature != "notes" {
+		t.Errorf("ReadNote() on mk2 got %v, want UnsupportedError", err)
+	}
+	if err := d2.WriteNote(0, []byte("hello")); err == nil {
+		t.Errorf("WriteNote() on mk2 should fail")
+	}
+	if _, err := d2.ReadChipID(); err == nil {
+		t.Errorf("ReadChipID() on mk2 should fail")
+	}
+
+	// mk3 commands
+	d3, err := b1.NewDevice(b1.NewEmulator(3, "EMU00003"))
+	if err != nil {
+		t.Fatalf("NewDevice() got unexpected error: %v", err)
+	}
+	defer d3.Close()
+
+	note := bytes.Repeat([]byte("0123456789"), 10)
+	if err := d3.WriteNote(3, note); err != nil {
+		t.Fatalf("WriteNote() got unexpected error: %v", err)
+	}
+	if got, err := d3.ReadNote(3); err != nil || !bytes.Equal(got, note) {
+		t.Errorf("ReadNote() = %q, %v, want %q", got, err, note)
+	}
+	if err := d3.WriteNote(4, []byte("short")); err != nil {
+		t.Fatalf("WriteNote() got unexpected error: %v", err)
+	}
+	if got, err := d3.ReadNote(4); err != nil || len(got) != 100 || string(bytes.TrimRight(got, "\x00")) != "short" {
+		t.Errorf("ReadNote() = %q, %v, want %q", got, err, "short")
+	}
+	if _, err := d3.ReadNote(10); err == nil {
+		t.Errorf("ReadNote() with invalid note id should fail")
+	}
+	if id, err := d3.ReadChipID(); err != nil || len(id) != 8 || string(id) != "EMU00003" {
+		t.Errorf("ReadChipID() = %q, %v", id, err)
+	}
+	if ver, err := d3.GetVersion(); err != nil || ver != 304 {
+		t.Errorf("GetVersion() = %d, %v, want 304", ver, err)
+	}
+}
+
+func TestDevice_EEPROM(t *testing.T) {
+	d, err := b1.NewDevice(b1.NewEmulator(2, "2000A1B2"))
+	if err != nil {
+		t.Fatalf("NewDevice() got unexpected error: %v", err)
+	}
+	defer d.Close()
+
+	if err := d.WriteEEPROM(0xf0, 0x2a); err != nil {
+		t.Fatalf("WriteEEPROM() got unexpected error: %v", err)
+	}
+	if val, err := d.ReadEEPROM(0xf0); err != nil || val != 0x2a {
+		t.Errorf("ReadEEPROM() = %x, %v, want 2a", val, err)
+	}
+	if err := d.WriteEEPROM(0x100, 0x2a); err == nil {
+		t.Errorf("WriteEEPROM() with invalid address should fail")
+	}
+	if _, err := d.ReadEEPROM(0x100); err == nil {
+		t.Errorf("ReadEEPROM() with invalid address should fail")
+	}
+
+	ly, err := d.ReadEEPROMLayout()
+	if err != nil {
+		t.Fatalf("ReadEEPROMLayout() got unexpected error: %v", err)
+	}
+	if want := [4]byte{0x20, 0x00, 0xa1, 0xb2}; ly.SerialNumber != want {
+		t.Errorf("ReadEEPROMLayout() got serial number %X, want %X", ly.SerialNumber, want)
+	}
+	if s, want := ly.String(), "💾{osccal=0x00 boot=0x00 sn=2000A1B2}"; s != want {
+		t.Errorf("DeviceEEPROMLayout.String() = %q, want %q", s, want)
+	}
+}
+
+func TestDevice_Reconnect(t *testing.T) {
+	emu := b1.NewEmulator(2, "EMU00001")
+	tr, err := emu.Open()
+	if err != nil {
+		t.Fatalf("Emulator.Open() got unexpected error: %v", err)
+	}
+	d, err := b1.NewDevice(tr)
+	if err != nil {
+		t.Fatalf("NewDevice() got unexpected error: %v", err)
+	}
+	defer d.Close()
+
+	// without resilient mode
+	emu.PowerCycle()
+	if _, err := d.GetVersion(); err == nil {
+		t.Fatalf("GetVersion() with stale handle should fail")
+	}
+
+	// with resilient mode
+	d.SetReconnectFunc(emu.Open)
+	if _, err := d.GetVersion(); err != nil {
+		t.Fatalf("GetVersion() should reconnect, got error: %v", err)
+	}
+	if err := d.SetPatternLine(1, b1.DeviceLightState{G: 0xff, LED: b1.LED2, FadeTimeMsec: 100}); err != nil {
+		t.Fatalf("SetPatternLine() got unexpected error: %v", err)
+	}
+	if err := d.FadeToRGB(0xff, 0, 0, 0, b1.LEDAll); err != nil {
+		t.Fatalf("FadeToRGB() got unexpected error: %v", err)
+	}
+	if err := d.FadeToRGB(0, 0, 0xff, 0, b1.LED2); err != nil {
+		t.Fatalf("FadeToRGB() got unexpected error: %v", err)
+	}
+	if err := d.SetTickleMode(true, true, 1, 1, 200); err != nil {
+		t.Fatalf("SetTickleMode() got unexpected error: %v", err)
+	}
+
+	// unplugged: failed to reconnect
+	emu.Unplug()
+	if _, _, _, err := d.ReadRGB(b1.LED1); err == nil {
+		t.Fatalf("ReadRGB() on unplugged device should fail")
+	}
+
+	// plugged: reconnect and replay
+	emu.Plug()
+	if r, g, b, err := d.ReadRGB(b1.LED1); err != nil || r != 0xff || g != 0 || b != 0 {
+		t.Errorf("ReadRGB(LED1) after replug = %x %x %x, %v, want ff 00 00", r, g, b, err)
+	}
+	if r, g, b, err := d.ReadRGB(b1.LED2); err != nil || r != 0 || g != 0 || b != 0xff {
+		t.Errorf("ReadRGB(LED2) after replug = %x %x %x, %v, want 00 00 ff", r, g, b, err)
+	}
+	if st, err := d.ReadPatternLine(1); err != nil || st.G != 0xff || st.LED != b1.LED2 || st.FadeTimeMsec != 100 {
+		t.Errorf("ReadPatternLine(1) after replug = %v, %v", st, err)
+	}
+	time.Sleep(250 * time.Millisecond)
+	if st, err := d.ReadPlaystate(); err != nil || !st.IsPlaying || st.LoopStartPos != 1 {
+		t.Errorf("ReadPlaystate() after tickle timeout = %v, %v, want playing", st, err)
+	}
+}
+
+func TestDevice_ReconnectOnlyOnDisconnect(t *testing.T) {
+	ft := newFakeTransport(2)
+	d, err := b1.NewDevice(ft)
+	if err != nil {
+		t.Fatalf("NewDevice() got unexpected error: %v", err)
+	}
+	defer d.Close()
+
+	reopens := 0
+	d.SetReconnectFunc(func() (b1.Transport, error) {
+		reopens++
+		return newFakeTransport(2), nil
+	})
+	if err := d.FadeToRGB(0xff, 0, 0, 0, b1.LEDAll); err != nil {
+		t.Fatalf("FadeToRGB() got unexpected error: %v", err)
+	}
+
+	// non-disconnect error: no reconnect and replay
+	errTimeout := errors.New("i/o timeout")
+	ft.failErr = errTimeout
+	if err := d.FadeToRGB(0, 0xff, 0, 0, b1.LEDAll); !errors.Is(err, errTimeout) {
+		t.Errorf("FadeToRGB() with failing transport got error: %v, want %v", err, errTimeout)
+	}
+	if reopens != 0 || ft.closed {
+		t.Errorf("non-disconnect error got %d reopens, closed=%v, want no reconnect", reopens, ft.closed)
+	}
+
+	// disconnect error: reconnect and retry
+	ft.failErr = b1.ErrDisconnected
+	if err := d.FadeToRGB(0, 0, 0xff, 0, b1.LEDAll); err != nil {
+		t.Errorf("FadeToRGB() should reconnect, got error: %v", err)
+	}
+	if reopens != 1 || !ft.closed {
+		t.Errorf("disconnect error got %d reopens, closed=%v, want one reconnect", reopens, ft.closed)
+	}
+}
+
+func TestDevice_Errors(t *testing.T) {
+	emu := b1.NewEmulator(2, "EMU00001")
+	tr, err := emu.Open()
+	if err != nil {
+		t.Fatalf("Emulator.Open() got unexpected error: %v", err)
+	}
+	d, err := b1.NewDevice(tr)
+	if err != nil {
+		t.Fatalf("NewDevice() got unexpected error: %v", err)
+	}
+	defer d.Close()
+
+	// invalid arguments and unsupported features
+	if err := d.SetPatternLine(32, b1.DeviceLightState{}); !errors.Is(err, b1.ErrInvalidPosition) {
+		t.Errorf("SetPatternLine(32) got error: %v, want ErrInvalidPosition", err)
+	}
+	if _, err := d.ReadEEPROM(0x100); !errors.Is(err, b1.ErrInvalidArgument) {
+		t.Errorf("ReadEEPROM(0x100) got error: %v, want ErrInvalidArgument", err)
+	}
+	if _, err := d.ReadChipID(); !errors.Is(err, b1.ErrUnsupported) {
+		t.Errorf("ReadChipID() on mk2 got error: %v, want ErrUnsupported", err)
+	}
+
+	// disconnected device
+	emu.Unplug()
+	err = d.FadeToRGB(0xff, 0, 0, 0, b1.LEDAll)
+	if !errors.Is(err, b1.ErrDisconnected) {
+		t.Errorf("FadeToRGB() on unplugged device got error: %v, want ErrDisconnected", err)
+	}
+	var ce *b1.CommandError
+	if !errors.As(err, &ce) {
+		t.Fatalf("FadeToRGB() on unplugged device got error: %v, want CommandError", err)
+	}
+	if ce.Op != "write" || ce.Cmd != 'c' || ce.Gen != 2 {
+		t.Errorf("CommandError = %+v, want write 'c' on mk2", ce)
+	}
+	if _, err := d.GetVersion(); !errors.As(err, &ce) || ce.Op != "read" || ce.Cmd != 'v' {
+		t.Errorf("GetVersion() on unplugged device got error: %v, want CommandError of read 'v'", err)
+	}
+}
@@ -0,0 +1,530 @@
+package blink1
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	hid "github.com/b1ug/gid"
+)
+
+// Emulator is a virtual blink(1) device which speaks the same HID feature report protocol as the firmware.
+// It models the pattern RAM, two LEDs with fade interpolation over time, play loops with repeat counts and the server-down tickle timeout.
+// The emulator implements Transport, so it can be used by NewDevice() and NewControllerWithTransport() to run the Device API and Controller API without hardware.
+//
+// The state is evaluated lazily against the wall clock, i.e. fades and patterns progress in real time between commands just like a real device.
+type Emulator struct {
+	mu   sync.Mutex
+	info *hid.DeviceInfo
+	now  func() time.Time
+
+	// profile
+	gen      uint16
+	verMajor byte
+	verMinor byte
+	pattMax  uint
+
+	// connection state, the epoch increases on every unplugging
+	unplugged bool
+	epoch     uint
+
+	// last response for reading feature report
+	resp []byte
+
+	// lights & pattern
+	leds    [2]emuLED
+	pattern []emuPatternLine
+	flash   []emuPatternLine
+	ledn    byte
+
+	// eeprom & startup params
+	eeprom    [maxEEPROM]byte
+	bootPlay  bool
+	bootStart byte
+	bootEnd   byte
+	bootCount byte
+
+	// mk3+ storage
+	notes  [maxNote][noteSize]byte
+	chipID [chipIDSize]byte
+
+	// play state
+	playing   bool
+	playStart uint
+	playEnd   uint // exclusive
+	playCount uint
+	playPos   uint
+	playNext  time.Time
+
+	// server-down tickle state
+	serverDown   bool
+	serverDownAt time.Time
+}
+
+var (
+	errEmulatorUnplugged = fmt.Errorf("%w: emulator is unplugged", ErrDisconnected)
+	errEmulatorStale     = fmt.Errorf("%w: emulator handle is stale", ErrDisconnected)
+)
+
+// emuHandle is an opened handle of the emulator, which becomes stale after unplugging.
+type emuHandle struct {
+	e     *Emulator
+	epoch uint
+}
+
+func (h *emuHandle) WriteFeature(buf []byte) error {
+	return h.e.writeFeature(buf, h.epoch, true)
+}
+
+func (h *emuHandle) ReadFeature(buf []byte) (int, error) {
+	return h.e.readFeature(buf, h.epoch, true)
+}
+
+func (h *emuHandle) Close() {}
+
+func (h *emuHandle) GetDeviceInfo() *hid.DeviceInfo {
+	return h.e.info
+}
+
+// emuPatternLine is a pattern line stored in the emulated pattern RAM.
+type emuPatternLine struct {
+	rgb  [3]byte
+	fade time.Duration
+	ledn byte
+}
+
+// emuLED is an emulated RGB LED fading from one color to another.
+type emuLED struct {
+	from  [3]byte
+	to    [3]byte
+	start time.Time
+	dur   time.Duration
+}
+
+// colorAt returns the color of the LED at the given time.
+func (l *emuLED) colorAt(t time.Time) (c [3]byte) {
+	el := t.Sub(l.start)
+	if l.dur <= 0 || el >= l.dur {
+		return l.to
+	}
+	if el < 0 {
+		return l.from
+	}
+	for i := range c {
+		diff := float64(int(l.to[i]) - int(l.from[i]))
+		c[i] = byte(int(l.from[i]) + int(diff*float64(el)/float64(l.dur)))
+	}
+	return c
+}
+
+// setDest starts fading the LED from its current color to the given color.
+func (l *emuLED) setDest(c [3]byte, dur time.Duration, t time.Time) {
+	l.from = l.colorAt(t)
+	l.to = c
+	l.start = t
+	l.dur = dur
+}
+
+// NewEmulator creates a virtual blink(1) device of the given generation (1=mk1, 2=mk2, 3=mk3 etc.) and serial number.
+// The firmware version defaults to the latest known version of the generation, and it can be changed by SetFirmwareVersion().
+func NewEmulator(gen uint16, sn string) *Emulator {
+	if gen == 0 {
+		gen = 1
+	}
+	e := &Emulator{
+		now:     time.Now,
+		gen:     gen,
+		pattMax: getMaxPattern(gen),
+	}
+
+	// profile
+	var pn string
+	switch gen {
+	case 1:
+		pn = "blink(1)"
+		e.verMajor, e.verMinor = '1', '5'
+	case 2:
+		pn = "blink(1) mk2"
+		e.verMajor, e.verMinor = '2', '4'
+	default:
+		pn = fmt.Sprintf("blink(1) mk%d", gen)
+		e.verMajor, e.verMinor = '3', '4'
+	}
+	e.info = &hid.DeviceInfo{
+		Path:          "emulator:" + sn,
+		VendorID:      b1VendorID,
+		ProductID:     b1ProductID,
+		VersionNumber: gen,
+		Manufacturer:  "ThingM",
+		Product:       pn,
+		SerialNumber:  sn,
+	}
+
+	// memory
+	e.pattern = make([]emuPatternLine, e.pattMax)
+	e.flash = make([]emuPatternLine, e.pattMax)
+	e.playEnd = e.pattMax
+	if raw, err := hex.DecodeString(sn); err == nil {
+		copy(e.eeprom[eeAddrSerial:eeAddrSerial+4], raw)
+	}
+	for i := 0; i < len(sn) && i < chipIDSize; i++ {
+		e.chipID[i] = sn[i]
+	}
+	return e
+}
+
+func (e *Emulator) String() string {
+	return fmt.Sprintf("👾{dev=%q gen=%d sn=%s}", e.info.Product, e.gen, e.info.SerialNumber)
+}
+
+// SetFirmwareVersion sets the firmware version reported by the emulator, the version is given as two digits, e.g. 2, 4 for v204.
+func (e *Emulator) SetFirmwareVersion(major, minor byte) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.verMajor, e.verMinor = '0'+major%10, '0'+minor%10
+}
+
+// Open opens a handle of the virtual device as a transport, like opening a HID device.
+// Unlike the emulator itself, the handle becomes stale once the virtual device is unplugged, and all operations on it will fail even after it's plugged again.
+func (e *Emulator) Open() (Transport, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.unplugged {
+		return nil, errEmulatorUnplugged
+	}
+	return &emuHandle{e: e, epoch: e.epoch}, nil
+}
+
+// Unplug simulates unplugging the virtual device, all operations will fail until it's plugged again.
+func (e *Emulator) Unplug() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.unplugged = true
+	e.epoch++
+}
+
+// Plug simulates plugging the virtual device back after Unplug(), and the device is powered on like PowerCycle().
+func (e *Emulator) Plug() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.unplugged = false
+	e.powerOn()
+}
+
+// PowerCycle simulates unplugging and re-plugging the virtual device at once.
+// The pattern RAM is reloaded from flash, all LEDs are turned off, and the pattern loop is played if it's enabled by startup params.
+func (e *Emulator) PowerCycle() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.epoch++
+	e.powerOn()
+}
+
+// powerOn resets the state of the virtual device as it's powered on.
+func (e *Emulator) powerOn() {
+	now := e.now()
+	copy(e.pattern, e.flash)
+	e.resp = nil
+	e.ledn = 0
+	e.serverDown = false
+	e.off(now)
+	if e.bootPlay {
+		e.handle(now, []byte{reportID, 'p', 1, e.bootStart, e.bootEnd, e.bootCount, 0, 0, 0})
+	}
+}
+
+// GetDeviceInfo returns the HID device info of the virtual device.
+func (e *Emulator) GetDeviceInfo() *hid.DeviceInfo {
+	return e.info
+}
+
+// Close does nothing, since the virtual device keeps its state like a plugged-in device, and it can be used again by other Device instances.
+func (e *Emulator) Close() {}
+
+// WriteFeature handles the feature report sent to the virtual device.
+func (e *Emulator) WriteFeature(buf []byte) error {
+	return e.writeFeature(buf, 0, false)
+}
+
+// ReadFeature returns the response of the last feature report sent to the virtual device.
+func (e *Emulator) ReadFeature(buf []byte) (int, error) {
+	return e.readFeature(buf, 0, false)
+}
+
+// writeFeature handles the feature report, and verifies the epoch of handle if needed.
+func (e *Emulator) writeFeature(buf []byte, epoch uint, checkEpoch bool) error {
+	if len(buf) < cmdBufSize || (buf[0] == report3ID && len(buf) < cmdBuf3Size) {
+		return fmt.Errorf("b1: emulator got short report: %d bytes", len(buf))
+	}
+	if buf[0] == report3ID && e.gen < 3 {
+		return fmt.Errorf("b1: emulator got unsupported report id %d for mk%d", buf[0], e.gen)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.checkConn(epoch, checkEpoch); err != nil {
+		return err
+	}
+	now := e.now()
+	e.advance(now)
+	e.resp = e.handle(now, append([]byte(nil), buf...))
+	return nil
+}
+
+// readFeature returns the response of the last feature report, and verifies the epoch of handle if needed.
+func (e *Emulator) readFeature(buf []byte, epoch uint, checkEpoch bool) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.checkConn(epoch, checkEpoch); err != nil {
+		return 0, err
+	}
+
+	if e.resp == nil {
+		for i := range buf {
+			buf[i] = 0
+		}
+		return len(buf), nil
+	}
+	return copy(buf, e.resp), nil
+}
+
+// checkConn returns an error if the virtual device is unplugged or the handle is stale.
+func (e *Emulator) checkConn(epoch uint, checkEpoch bool) error {
+	if e.unplugged {
+		return errEmulatorUnplugged
+	}
+	if checkEpoch && epoch != e.epoch {
+		return errEmulatorStale
+	}
+	return nil
+}
+
+// handle executes the command in the message buffer and returns the response.
+func (e *Emulator) handle(now time.Time, msg []byte) []byte {
+	if msg[0] == report3ID {
+		return e.handle3(msg)
+	}
+
+	switch msg[1] {
+	case 'c':
+		// fade to rgb: {1, 'c', r,g,b, th,tl, ledn}
+		e.setLEDs(msg[7], [3]byte{msg[2], msg[3], msg[4]}, convFadeMsToDuration(msg[5], msg[6]), now)
+	case 'n':
+		// set rgb now: {1, 'n', r,g,b, 0,0, ledn}
+		c := [3]byte{msg[2], msg[3], msg[4]}
+		if e.gen == 2 && msg[7] > 0 {
+			// firmware bug of mk2: all LEDs will be set to white
+			c, msg[7] = [3]byte{0xff, 0xff, 0xff}, 0
+		}
+		e.setLEDs(msg[7], c, 0, now)
+	case 'r':
+		// read current rgb: {1, 'r', 0,0,0, 0,0, ledn}
+		i := 0
+		if e.gen >= 2 && msg[7] == 2 {
+			i = 1
+		}
+		c := e.leds[i].colorAt(now)
+		msg[2], msg[3], msg[4] = c[0], c[1], c[2]
+	case 'p':
+		// play/pause loop: {1, 'p', {0/1}, startpos, endpos, count}
+		e.playing = convByteToBool(msg[2])
+		e.playStart = uint(msg[3])
+		e.playEnd = uint(msg[4])
+		if e.playEnd == 0 || e.playEnd > e.pattMax {
+			e.playEnd = e.pattMax
+		} else {
+			e.playEnd++
+		}
+		e.playCount = uint(msg[5])
+		e.startPlaying(now)
+	case 'S':
+		// read playstate: {1, 'S', 0,0,0, 0,0,0}
+		msg[2] = convBoolToByte(e.playing)
+		msg[3], msg[4] = byte(e.playStart), byte(e.playEnd)
+		msg[5], msg[6] = byte(e.playCount), byte(e.playPos)
+	case 'l':
+		// set ledn for next pattern line: {1, 'l', ledn}
+		if e.gen >= 2 {
+			e.ledn = msg[2]
+		}
+	case 'P':
+		// set pattern line: {1, 'P', r,g,b, th,tl, pos}
+		if pos := uint(msg[7]); pos < e.pattMax {
+			e.pattern[pos] = emuPatternLine{
+				rgb:  [3]byte{msg[2], msg[3], msg[4]},
+				fade: convFadeMsToDuration(msg[5], msg[6]),
+				ledn: e.ledn,
+			}
+		}
+	case 'R':
+		// read pattern line: {1, 'R', 0,0,0, 0,0, pos}
+		if pos := uint(msg[7]); pos < e.pattMax {
+			l := e.pattern[pos]
+			msg[2], msg[3], msg[4] = l.rgb[0], l.rgb[1], l.rgb[2]
+			msg[5], msg[6] = convDurationToFadeMs(l.fade)
+			msg[7] = l.ledn
+		}
+	case 'W':
+		// save patterns to flash: {1, 'W', 0xBE, 0xEF, 0xCA, 0xFE}
+		if msg[2] == 0xBE && msg[3] == 0xEF && msg[4] == 0xCA && msg[5] == 0xFE {
+			n := e.pattMax
+			if e.gen == 2 {
+				// only the first 16 lines can be saved on mk2
+				n = 16
+			}
+			copy(e.flash[:n], e.pattern[:n])
+		}
+	case 'D':
+		// server tickle: {1, 'D', on, th,tl, st, startpos, endpos}
+		e.playStart = uint(msg[6])
+		e.playEnd = uint(msg[7])
+		if e.playEnd == 0 || e.playEnd > e.pattMax {
+			e.playEnd = e.pattMax
+		}
+		if convByteToBool(msg[2]) {
+			e.serverDown = true
+			e.serverDownAt = now.Add(convFadeMsToDuration(msg[3], msg[4]))
+		} else {
+			e.serverDown = false
+		}
+		if !convByteToBool(msg[5]) {
+			e.off(now)
+		}
+	case 'B':
+		// set startup params: {1, 'B', bootmode, playstart, playend, playcount}
+		if e.hasStartupParams() {
+			e.bootPlay = convByteToBool(msg[2])
+			e.bootStart, e.bootEnd, e.bootCount = msg[3], msg[4], msg[5]
+		}
+	case 'b':
+		// get startup params: {1, 'b'}
+		if e.hasStartupParams() {
+			msg[2] = convBoolToByte(e.bootPlay)
+			msg[3], msg[4], msg[5] = e.bootStart, e.bootEnd, e.bootCount
+		}
+	case 'e':
+		// read eeprom: {1, 'e', addr}
+		msg[3] = e.eeprom[msg[2]]
+	case 'E':
+		// write eeprom: {1, 'E', addr, val}
+		e.eeprom[msg[2]] = msg[3]
+	case 'v':
+		// get version: {1, 'v', 0, ver_major, ver_minor}
+		msg[3], msg[4] = e.verMajor, e.verMinor
+	case '!':
+		// test: {1, '!', 0x55, 0xAA, ...}
+		msg[2], msg[3] = 0x55, 0xAA
+	}
+	return msg
+}
+
+// handle3 executes the mk3+ command with report ID 2 in the message buffer and returns the response.
+func (e *Emulator) handle3(msg []byte) []byte {
+	switch msg[1] {
+	case 'F':
+		// write note: {2, 'F', noteid, offset, data...}
+		if id, off := uint(msg[2]), int(msg[3]); id < maxNote && off+noteChunk <= noteSize {
+			copy(e.notes[id][off:off+noteChunk], msg[4:])
+		}
+	case 'f':
+		// read note: {2, 'f', noteid, offset}
+		if id, off := uint(msg[2]), int(msg[3]); id < maxNote && off+noteChunk <= noteSize {
+			copy(msg[4:], e.notes[id][off:off+noteChunk])
+		}
+	case 'U':
+		// read chip unique id: {2, 'U'}
+		copy(msg[2:], e.chipID[:])
+	}
+	return msg
+}
+
+// hasStartupParams returns true if the firmware supports startup params.
+func (e *Emulator) hasStartupParams() bool {
+	fi := FirmwareInfo{
+		Major:      uint(e.verMajor - '0'),
+		Minor:      uint(e.verMinor - '0'),
+		Generation: e.gen,
+	}
+	return fi.Capabilities().StartupParams
+}
+
+// setLEDs starts fading the given LEDs to the color.
+func (e *Emulator) setLEDs(ledn byte, c [3]byte, dur time.Duration, now time.Time) {
+	if e.gen < 2 {
+		// only one color for all LEDs on mk1
+		ledn = 0
+	}
+	for i := range e.leds {
+		if ledn == 0 || int(ledn) == i+1 {
+			e.leds[i].setDest(c, dur, now)
+		}
+	}
+}
+
+// off stops playing and turns off all LEDs immediately.
+func (e *Emulator) off(now time.Time) {
+	e.playing = false
+	e.setLEDs(0, [3]byte{}, 0, now)
+}
+
+// startPlaying starts playing the loop from the start position.
+func (e *Emulator) startPlaying(now time.Time) {
+	e.playPos = e.playStart
+	e.playNext = now
+}
+
+// advance evaluates the timed events of playing pattern and server-down tickle until the given time.
+func (e *Emulator) advance(now time.Time) {
+	for {
+		// find the earliest event
+		var (
+			at     time.Time
+			isDown bool
+		)
+		if e.serverDown {
+			at, isDown = e.serverDownAt, true
+		}
+		if e.playing && (!isDown || e.playNext.Before(at)) {
+			at, isDown = e.playNext, false
+		}
+		if (!e.serverDown && !e.playing) || at.After(now) {
+			return
+		}
+
+		// server is down: play the loop forever
+		if isDown {
+			e.serverDown = false
+			e.playing = true
+			e.playCount = 0
+			e.startPlaying(at)
+			continue
+		}
+
+		// play the next line
+		l := e.pattern[e.playPos%e.pattMax]
+		e.setLEDs(l.ledn, l.rgb, l.fade, at)
+		if e.playPos++; e.playPos == e.playEnd {
+			e.playPos = e.playStart
+			if e.playCount > 0 {
+				if e.playCount--; e.playCount == 0 {
+					e.playing = false
+				}
+			}
+		} else if e.playPos >= e.pattMax {
+			e.playPos = 0
+		}
+		// the firmware updates every 10ms, it's the minimum time to a line
+		dur := l.fade
+		if dur < minTimeDur {
+			dur = minTimeDur
+		}
+		e.playNext = at.Add(dur)
+	}
+}
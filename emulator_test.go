@@ -0,0 +1,178 @@
+package blink1_test
+
+import (
+	"testing"
+	"time"
+
+	b1 "github.com/b1ug/blink1-go"
+)
+
+func newEmulatedController(t *testing.T, gen uint16) *b1.Controller {
+	c, err := b1.NewControllerWithTransport(b1.NewEmulator(gen, "EMU00001"))
+	if err != nil {
+		t.Fatalf("NewControllerWithTransport() got unexpected error: %v", err)
+	}
+	c.SetGammaCorrection(false)
+	return c
+}
+
+func TestEmulator_Profile(t *testing.T) {
+	for _, gen := range []uint16{1, 2, 3} {
+		emu := b1.NewEmulator(gen, "EMU00001")
+		d, err := b1.NewDevice(emu)
+		if err != nil {
+			t.Fatalf("NewDevice(%v) got unexpected error: %v", emu, err)
+		}
+		if d.GetGeneration() != gen || d.GetSerialNumber() != "EMU00001" {
+			t.Errorf("NewDevice(%v) got unexpected profile: %v", emu, d)
+		}
+		if ver, err := d.GetVersion(); err != nil || ver/100 != int(gen) {
+			t.Errorf("GetVersion() of mk%d = %d, %v", gen, ver, err)
+		}
+	}
+
+	emu := b1.NewEmulator(2, "EMU00002")
+	emu.SetFirmwareVersion(2, 3)
+	d, _ := b1.NewDevice(emu)
+	if ver, err := d.GetVersion(); err != nil || ver != 203 {
+		t.Errorf("GetVersion() = %d, %v, want 203", ver, err)
+	}
+	if buf, err := d.Test(); err != nil || buf[2] != 0x55 || buf[3] != 0xAA {
+		t.Errorf("Test() = %x, %v", buf, err)
+	}
+}
+
+func TestEmulator_Fade(t *testing.T) {
+	c := newEmulatedController(t, 2)
+	defer c.Close()
+
+	if err := c.PlayState(b1.NewLightState(b1.ColorRed, 200*time.Millisecond, b1.LED1)); err != nil {
+		t.Fatalf("PlayState() got unexpected error: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if cl, err := c.ReadColor(b1.LED1); err != nil || b1.ColorToHex(cl) == "#FF0000" || b1.ColorToHex(cl) == "#000000" {
+		t.Errorf("ReadColor() while fading = %v, %v", cl, err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if cl, err := c.ReadColor(b1.LED1); err != nil || b1.ColorToHex(cl) != "#FF0000" {
+		t.Errorf("ReadColor() after fading = %v, %v, want #FF0000", cl, err)
+	}
+	if cl, err := c.ReadColor(b1.LED2); err != nil || b1.ColorToHex(cl) != "#000000" {
+		t.Errorf("ReadColor() of untouched LED = %v, %v, want #000000", cl, err)
+	}
+
+	// mk2 firmware bug: set rgb now with ledN > 0 sets all LEDs to white
+	d := c.GetDevice()
+	if err := d.SetRGBNow(0, 0, 0xff, b1.LED2); err != nil {
+		t.Fatalf("SetRGBNow() got unexpected error: %v", err)
+	}
+	if r, g, b, err := d.ReadRGB(b1.LED1); err != nil || r != 0xff || g != 0xff || b != 0xff {
+		t.Errorf("ReadRGB() = %x %x %x, %v, want white", r, g, b, err)
+	}
+}
+
+func TestEmulator_Pattern(t *testing.T) {
+	c := newEmulatedController(t, 2)
+	defer c.Close()
+
+	seq := b1.StateSequence{
+		b1.NewLightState(b1.ColorRed, 50*time.Millisecond, b1.LED1),
+		b1.NewLightState(b1.ColorBlue, 50*time.Millisecond, b1.LED2),
+	}
+	pt := b1.Pattern{StartPosition: 2, EndPosition: 3, RepeatTimes: 2, Sequence: seq}
+
+	// read back the pattern
+	if err := c.LoadPattern(pt.StartPosition, pt.EndPosition, seq); err != nil {
+		t.Fatalf("LoadPattern() got unexpected error: %v", err)
+	}
+	ls, err := c.ReadPattern()
+	if err != nil {
+		t.Fatalf("ReadPattern() got unexpected error: %v", err)
+	}
+	if len(ls) != 32 || ls[2].String() != seq[0].String() || ls[3].String() != seq[1].String() {
+		t.Errorf("ReadPattern() = %v, want %v at [2,3]", ls, seq)
+	}
+
+	// play and block
+	st := time.Now()
+	if err := c.PlayPatternBlocking(pt); err != nil {
+		t.Fatalf("PlayPatternBlocking() got unexpected error: %v", err)
+	}
+	if el := time.Since(st); el < 200*time.Millisecond {
+		t.Errorf("PlayPatternBlocking() took %v, want >= 200ms", el)
+	}
+	time.Sleep(20 * time.Millisecond)
+	ps, err := c.GetPatternState()
+	if err != nil {
+		t.Fatalf("GetPatternState() got unexpected error: %v", err)
+	}
+	if ps.IsPlaying || ps.StartPosition != 2 || ps.EndPosition != 4 {
+		t.Errorf("GetPatternState() = %v, want stopped loop [2,4)", ps)
+	}
+	if cl, _ := c.ReadColor(b1.LED1); b1.ColorToHex(cl) != "#FF0000" {
+		t.Errorf("ReadColor(LED1) = %v, want #FF0000", cl)
+	}
+	if cl, _ := c.ReadColor(b1.LED2); b1.ColorToHex(cl) != "#0000FF" {
+		t.Errorf("ReadColor(LED2) = %v, want #0000FF", cl)
+	}
+
+	// infinite loop
+	pt.RepeatTimes = 0
+	if err := c.PlayPattern(pt); err != nil {
+		t.Fatalf("PlayPattern() got unexpected error: %v", err)
+	}
+	time.Sleep(250 * time.Millisecond)
+	if ok, err := c.IsPatternPlaying(); err != nil || !ok {
+		t.Errorf("IsPatternPlaying() = %v, %v, want true", ok, err)
+	}
+	if err := c.StopPlaying(); err != nil {
+		t.Fatalf("StopPlaying() got unexpected error: %v", err)
+	}
+	if ok, err := c.IsPatternPlaying(); err != nil || ok {
+		t.Errorf("IsPatternPlaying() after stop = %v, %v, want false", ok, err)
+	}
+	if cl, _ := c.ReadColor(b1.LED1); b1.ColorToHex(cl) != "#000000" {
+		t.Errorf("ReadColor(LED1) after stop = %v, want #000000", cl)
+	}
+}
+
+func TestEmulator_PatternRange(t *testing.T) {
+	c := newEmulatedController(t, 1)
+	defer c.Close()
+
+	seq := b1.StateSequence{b1.NewLightState(b1.ColorRed, 0, b1.LEDAll)}
+	if err := c.LoadPattern(0, 11, seq); err != nil {
+		t.Errorf("LoadPattern() for mk1 got unexpected error: %v", err)
+	}
+	if err := c.LoadPattern(0, 12, seq); err == nil {
+		t.Errorf("LoadPattern() for mk1 should fail for position 12")
+	}
+	if ls, err := c.ReadPattern(); err != nil || len(ls) != 12 {
+		t.Errorf("ReadPattern() for mk1 = %d lines, %v, want 12", len(ls), err)
+	}
+}
+
+func TestEmulator_Tickle(t *testing.T) {
+	c := newEmulatedController(t, 2)
+	defer c.Close()
+
+	st := b1.NewLightState(b1.ColorGreen, 0, b1.LEDAll)
+	if err := c.LoadPattern(0, 1, b1.StateSequence{st, st}); err != nil {
+		t.Fatalf("LoadPattern() got unexpected error: %v", err)
+	}
+	if err := c.SimpleTickle(0, 1, 100*time.Millisecond, false); err != nil {
+		t.Fatalf("SimpleTickle() got unexpected error: %v", err)
+	}
+	if ok, _ := c.IsPatternPlaying(); ok {
+		t.Errorf("IsPatternPlaying() before timeout = true, want false")
+	}
+
+	// server down: pattern starts
+	time.Sleep(150 * time.Millisecond)
+	if ok, _ := c.IsPatternPlaying(); !ok {
+		t.Errorf("IsPatternPlaying() after timeout = false, want true")
+	}
+	if cl, _ := c.ReadColor(b1.LED1); b1.ColorToHex(cl) != "#00FF00" {
+		t.Errorf("ReadColor() after timeout = %v, want #00FF00", cl)
+	}
+}
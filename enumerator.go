@@ -0,0 +1,191 @@
+package blink1
+
+import (
+	"strings"
+	"sync"
+
+	hid "github.com/b1ug/gid"
+)
+
+// Enumerator iterates the blink(1) devices connected to the system over its own snapshot, so independent enumerators in one process don't steal devices from each other.
+// The devices can be filtered by generation, serial number prefix and firmware version, and the snapshot is ordered by serial number.
+type Enumerator struct {
+	mu     sync.Mutex
+	source func() []*hid.DeviceInfo
+	opener func(*hid.DeviceInfo) (Transport, error)
+
+	// filters
+	gens   []uint16
+	prefix string
+	minFw  int
+
+	// snapshot
+	list []*hid.DeviceInfo // nil for no snapshot taken
+	next int               // index of the next device in the snapshot
+}
+
+// NewEnumerator creates an enumerator of the blink(1) devices connected to the system without filters.
+func NewEnumerator() *Enumerator {
+	return &Enumerator{
+		source: ListDeviceInfo,
+		opener: func(di *hid.DeviceInfo) (Transport, error) {
+			return openHIDTransport(di)
+		},
+	}
+}
+
+// SetSource sets the function to enumerate blink(1) devices, it's ListDeviceInfo() by default.
+// It takes effect on the next snapshot.
+func (e *Enumerator) SetSource(source func() []*hid.DeviceInfo) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.source = source
+	e.list = nil
+}
+
+// SetOpener sets the function to open the transport of a device for reading its firmware version, it opens the HID device by default.
+// It's only used by the firmware filter.
+func (e *Enumerator) SetOpener(opener func(*hid.DeviceInfo) (Transport, error)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.opener = opener
+	e.list = nil
+}
+
+// FilterGeneration keeps only the devices of the given generations, e.g. 2 for mk2, or all devices if no generations are given.
+// It takes effect on the next snapshot.
+func (e *Enumerator) FilterGeneration(gens ...uint16) *Enumerator {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.gens = append([]uint16(nil), gens...)
+	e.list = nil
+	return e
+}
+
+// FilterSerialPrefix keeps only the devices whose serial number starts with the given prefix case-insensitively, or all devices if it's empty.
+// It takes effect on the next snapshot.
+func (e *Enumerator) FilterSerialPrefix(prefix string) *Enumerator {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.prefix = strings.ToUpper(prefix)
+	e.list = nil
+	return e
+}
+
+// FilterFirmware keeps only the devices with firmware version at least the given one, e.g. 204 for v204, or all devices if it's not positive.
+// The devices are opened briefly to read the firmware version when taking the snapshot, and the ones failing to open are skipped.
+// It takes effect on the next snapshot.
+func (e *Enumerator) FilterFirmware(minVersion int) *Enumerator {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.minFw = minVersion
+	e.list = nil
+	return e
+}
+
+// Refresh takes a new snapshot of the devices and rewinds the iteration.
+func (e *Enumerator) Refresh() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.refresh()
+}
+
+// Next returns the HID device info of the next device in the snapshot, and the snapshot is taken on the first call.
+// When all devices have been returned, ErrDeviceNotFound is returned and the next call will take a new snapshot.
+func (e *Enumerator) Next() (*hid.DeviceInfo, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// no snapshot: need init or reset
+	if e.list == nil {
+		e.refresh()
+	}
+
+	// last device already returned
+	if e.next >= len(e.list) {
+		e.list = nil
+		return nil, ErrDeviceNotFound
+	}
+	di := e.list[e.next]
+	e.next++
+	return di, nil
+}
+
+// All returns the HID device info of all devices in the snapshot in order of serial number, and the snapshot is taken if there is none.
+// It doesn't move the iteration of Next().
+func (e *Enumerator) All() []*hid.DeviceInfo {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.list == nil {
+		e.refresh()
+	}
+	return append([]*hid.DeviceInfo(nil), e.list...)
+}
+
+// OpenNextDevice opens the next device in the snapshot and returns as device.
+func (e *Enumerator) OpenNextDevice() (*Device, error) {
+	di, err := e.Next()
+	if err != nil {
+		return nil, err
+	}
+	return OpenDevice(di)
+}
+
+// OpenNextController opens the next device in the snapshot and returns as controller.
+func (e *Enumerator) OpenNextController() (*Controller, error) {
+	di, err := e.Next()
+	if err != nil {
+		return nil, err
+	}
+	return OpenController(di)
+}
+
+// refresh takes a new snapshot of the filtered devices and rewinds the iteration.
+func (e *Enumerator) refresh() {
+	list := make([]*hid.DeviceInfo, 0)
+	for _, di := range sortDeviceInfo(e.source()) {
+		if e.match(di) {
+			list = append(list, di)
+		}
+	}
+	e.list = list
+	e.next = 0
+}
+
+// match returns true if the device passes all the filters.
+func (e *Enumerator) match(di *hid.DeviceInfo) bool {
+	if len(e.gens) > 0 {
+		found := false
+		for _, gen := range e.gens {
+			if di.VersionNumber == gen {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if e.prefix != "" && !strings.HasPrefix(strings.ToUpper(di.SerialNumber), e.prefix) {
+		return false
+	}
+	if e.minFw > 0 {
+		fi, err := e.firmware(di)
+		if err != nil || fi.Version() < e.minFw {
+			return false
+		}
+	}
+	return true
+}
+
+// firmware opens the device to read its firmware info.
+func (e *Enumerator) firmware(di *hid.DeviceInfo) (FirmwareInfo, error) {
+	tr, err := e.opener(di)
+	if err != nil {
+		return FirmwareInfo{}, err
+	}
+	d := newDevice(di, tr)
+	defer d.Close()
+	return d.GetFirmwareInfo()
+}
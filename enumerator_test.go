@@ -0,0 +1,104 @@
+package blink1_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	b1 "github.com/b1ug/blink1-go"
+	hid "github.com/b1ug/gid"
+)
+
+func TestEnumerator(t *testing.T) {
+	emus := map[string]*b1.Emulator{
+		"2000B002": b1.NewEmulator(2, "2000B002"),
+		"1000A001": b1.NewEmulator(1, "1000A001"),
+		"3000C001": b1.NewEmulator(3, "3000C001"),
+		"2000B001": b1.NewEmulator(2, "2000B001"),
+	}
+	emus["2000B001"].SetFirmwareVersion(2, 2)
+	emus["2000B002"].SetFirmwareVersion(2, 5)
+	source := func() []*hid.DeviceInfo {
+		var infos []*hid.DeviceInfo
+		for _, e := range emus {
+			infos = append(infos, e.GetDeviceInfo())
+		}
+		return infos
+	}
+	newEnum := func() *b1.Enumerator {
+		en := b1.NewEnumerator()
+		en.SetSource(source)
+		en.SetOpener(func(di *hid.DeviceInfo) (b1.Transport, error) {
+			return emus[di.SerialNumber].Open()
+		})
+		return en
+	}
+	serials := func(infos []*hid.DeviceInfo) []string {
+		sns := make([]string, len(infos))
+		for i, di := range infos {
+			sns[i] = di.SerialNumber
+		}
+		return sns
+	}
+
+	// independent iterations
+	en1, en2 := newEnum(), newEnum()
+	for _, want := range []string{"1000A001", "2000B001"} {
+		di, err := en1.Next()
+		if err != nil {
+			t.Fatalf("Next() got unexpected error: %v", err)
+		}
+		if di.SerialNumber != want {
+			t.Errorf("Next() got %s, want %s", di.SerialNumber, want)
+		}
+	}
+	if di, err := en2.Next(); err != nil || di.SerialNumber != "1000A001" {
+		t.Errorf("Next() of another enumerator got %v, %v, want 1000A001", di, err)
+	}
+	if got := serials(en1.All()); len(got) != 4 || got[0] != "1000A001" || got[3] != "3000C001" {
+		t.Errorf("All() got %v, want all devices in order", got)
+	}
+
+	// end of the snapshot and restart
+	for i := 0; i < 2; i++ {
+		if _, err := en1.Next(); err != nil {
+			t.Fatalf("Next() got unexpected error: %v", err)
+		}
+	}
+	if _, err := en1.Next(); !errors.Is(err, b1.ErrDeviceNotFound) {
+		t.Errorf("Next() after the last device got error: %v, want ErrDeviceNotFound", err)
+	}
+	if di, err := en1.Next(); err != nil || di.SerialNumber != "1000A001" {
+		t.Errorf("Next() after the end got %v, %v, want 1000A001", di, err)
+	}
+
+	// snapshot is kept until refresh
+	delete(emus, "1000A001")
+	if n := len(en1.All()); n != 4 {
+		t.Errorf("All() before Refresh() got %d devices, want 4", n)
+	}
+	en1.Refresh()
+	if got := serials(en1.All()); len(got) != 3 || got[0] != "2000B001" {
+		t.Errorf("All() after Refresh() got %v, want 3 devices", got)
+	}
+
+	// filters
+	tests := []struct {
+		name string
+		en   *b1.Enumerator
+		want string
+	}{
+		{"generation", newEnum().FilterGeneration(2), "[2000B001 2000B002]"},
+		{"generations", newEnum().FilterGeneration(1, 3), "[3000C001]"},
+		{"serial prefix", newEnum().FilterSerialPrefix("2000b"), "[2000B001 2000B002]"},
+		{"firmware", newEnum().FilterFirmware(204), "[2000B002 3000C001]"},
+		{"combined", newEnum().FilterGeneration(2).FilterFirmware(204), "[2000B002]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serials(tt.en.All()); fmt.Sprint(got) != tt.want {
+				t.Errorf("All() got %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package blink1
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrDeviceNotFound is returned when no connected blink(1) device matches the given condition.
+	ErrDeviceNotFound = errors.New("b1: device not found")
+	// ErrNotBlink1 is returned when the given HID device is not a blink(1) device.
+	ErrNotBlink1 = errors.New("b1: device is not blink(1)")
+	// ErrDisconnected is returned when the device is unplugged or its HID handle is no longer valid.
+	ErrDisconnected = errors.New("b1: device disconnected")
+	// ErrClosed is returned when an operation is performed on a closed device or controller.
+	ErrClosed = errors.New("b1: device closed")
+	// ErrUnsupported is returned when a feature is not supported by the firmware of the device, it's matched by UnsupportedError.
+	ErrUnsupported = errors.New("b1: feature unsupported")
+	// ErrInvalidArgument is returned when an argument is invalid, e.g. note id or EEPROM address out of range, or nil device info.
+	ErrInvalidArgument = errors.New("b1: invalid argument")
+	// ErrInvalidPosition is returned when the pattern position or range is out of the pattern RAM of the device.
+	ErrInvalidPosition = errors.New("b1: invalid pattern position")
+	// ErrInvalidRepeatTimes is returned when the pattern repeat times exceeds the limit.
+	ErrInvalidRepeatTimes = errors.New("b1: invalid pattern repeat times")
+	// ErrInvalidTimeout is returned when the tickle timeout is too short to be handled by the firmware.
+	ErrInvalidTimeout = errors.New("b1: invalid timeout")
+	// ErrPreempted is returned when a long upload of pattern lines is aborted by a queued command with higher priority.
+	ErrPreempted = errors.New("b1: command preempted")
+	// ErrLocked is returned when the device is exclusively owned by another process or handle, it's matched by LockedError.
+	ErrLocked = errors.New("b1: device locked")
+	// ErrAliasTaken is returned when the alias is already given to another device in the registry.
+	ErrAliasTaken = errors.New("b1: alias is taken")
+	// ErrInvalidSelector is returned when the device selector expression can't be parsed.
+	ErrInvalidSelector = errors.New("b1: invalid selector")
+)
+
+// CommandError is returned when a HID command fails to be sent to or read from the device, the underlying error can be inspected with errors.Is and errors.As.
+type CommandError struct {
+	Op  string // Operation of the command: "write" or "read"
+	Cmd byte   // Command character, e.g. 'c' for fading to RGB
+	Gen uint16 // Generation of the device: 1=mk1, 2=mk2, 3=mk3 etc.
+	Err error  // Underlying error from the transport or context
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("b1: %s %q on mk%d fail: %v", e.Op, e.Cmd, e.Gen, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// newCommandError wraps the error of the command in the buffer as CommandError.
+func newCommandError(op string, buf []byte, gen uint16, err error) *CommandError {
+	var cmd byte
+	if len(buf) > 1 {
+		cmd = buf[1]
+	}
+	return &CommandError{Op: op, Cmd: cmd, Gen: gen, Err: err}
+}
@@ -1,6 +1,7 @@
 package blink1_test
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -135,3 +136,41 @@ func ExampleParseStateQuery() {
 	// Output:
 	// 🎨(color=#0000FF led=1 fade=1.5s)
 }
+
+// This example shows how to drive a virtual blink(1) device with the Controller API, without any hardware.
+func ExampleNewEmulator() {
+	emu := b1.NewEmulator(2, "20001234")
+	c, err := b1.NewControllerWithTransport(emu)
+	if err != nil {
+		panic(err)
+	}
+	defer c.Close()
+
+	if err := c.PlayColor(b1.ColorRed); err != nil {
+		panic(err)
+	}
+	if cl, err := c.ReadColor(b1.LED1); err != nil {
+		panic(err)
+	} else {
+		fmt.Println(b1.GetNameOrHexByColor(cl))
+	}
+
+	// Output:
+	// red
+}
+
+// This example shows how to watch blink(1) devices being plugged in and removed.
+func ExampleWatcher_Watch() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	w := b1.NewWatcher(time.Second)
+	for ev := range w.Watch(ctx) {
+		switch ev.Type {
+		case b1.DeviceAdded:
+			fmt.Println("plugged in:", ev.SerialNumber)
+		case b1.DeviceRemoved:
+			fmt.Println("removed:", ev.SerialNumber)
+		}
+	}
+}
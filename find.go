@@ -3,23 +3,30 @@ package blink1
 import (
 	"fmt"
 	"sort"
-	"sync"
 
 	hid "github.com/b1ug/gid"
 )
 
-var (
-	devInfoMu   sync.RWMutex
-	devInfoList []*hid.DeviceInfo
-)
+// defaultEnumerator is the enumerator used by FindNextDeviceInfo, OpenNextDevice and OpenNextController.
+var defaultEnumerator = NewEnumerator()
 
 // ListDeviceInfo returns all HID device info of all blink(1) devices which are connected to the system. The returned slice is sorted by serial number.
 func ListDeviceInfo() []*hid.DeviceInfo {
-	infos := hid.ListAllDevices(IsBlink1Device)
-	sort.SliceStable(infos, func(i, j int) bool {
-		return infos[i].SerialNumber < infos[j].SerialNumber
+	return sortDeviceInfo(hid.ListAllDevices(IsBlink1Device))
+}
+
+// sortDeviceInfo returns a copy of the HID device info of blink(1) devices only, sorted by serial number.
+func sortDeviceInfo(infos []*hid.DeviceInfo) []*hid.DeviceInfo {
+	sorted := make([]*hid.DeviceInfo, 0, len(infos))
+	for _, di := range infos {
+		if IsBlink1Device(di) {
+			sorted = append(sorted, di)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].SerialNumber < sorted[j].SerialNumber
 	})
-	return infos
+	return sorted
 }
 
 // FindDeviceInfoBySerialNumber finds a connected blink(1) device with serial number and returns its HID device info.
@@ -29,53 +36,25 @@ func FindDeviceInfoBySerialNumber(sn string) (*hid.DeviceInfo, error) {
 	})
 	// not found
 	if dev == nil {
-		return nil, fmt.Errorf("%w for %q", errDeviceNotFound, sn)
+		return nil, fmt.Errorf("%w for %q", ErrDeviceNotFound, sn)
 	}
 	return dev, nil
 }
 
 // FindNextDeviceInfo returns the next HID device info of a blink(1) device which is connected to the system.
-func FindNextDeviceInfo() (di *hid.DeviceInfo, err error) {
-	devInfoMu.Lock()
-	defer devInfoMu.Unlock()
-
-	// nil list: need init or reset
-	if devInfoList == nil {
-		devInfoList = ListDeviceInfo()
-	}
-
-	// empty list: last device already returned
-	if len(devInfoList) == 0 {
-		devInfoList = nil
-		return nil, errDeviceNotFound
-	}
-	di = devInfoList[0]
-	devInfoList = devInfoList[1:]
-	return di, nil
+// It iterates a default Enumerator shared by the package, use NewEnumerator() for an independent iteration.
+func FindNextDeviceInfo() (*hid.DeviceInfo, error) {
+	return defaultEnumerator.Next()
 }
 
 // OpenNextDevice opens the next blink(1) device which is connected to the system and returns as device.
 func OpenNextDevice() (*Device, error) {
-	// find
-	di, err := FindNextDeviceInfo()
-	if err != nil {
-		return nil, err
-	}
-
-	// open
-	return OpenDevice(di)
+	return defaultEnumerator.OpenNextDevice()
 }
 
 // OpenNextController opens the next blink(1) device which is connected to the system and returns as controller.
 func OpenNextController() (*Controller, error) {
-	// find
-	di, err := FindNextDeviceInfo()
-	if err != nil {
-		return nil, err
-	}
-
-	// open
-	return OpenController(di)
+	return defaultEnumerator.OpenNextController()
 }
 
 // OpenDeviceBySerialNumber finds a connected blink(1) device with serial number and opens it as device.
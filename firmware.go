@@ -0,0 +1,64 @@
+package blink1
+
+import (
+	"fmt"
+)
+
+// FirmwareInfo represents the firmware version of a blink(1) device along with its generation.
+type FirmwareInfo struct {
+	Major      uint   // Major version, e.g. 2 for v204
+	Minor      uint   // Minor version, e.g. 4 for v204
+	Raw        []byte // Raw version bytes in ASCII digits from the device
+	Generation uint16 // Generation of the device: 1=mk1, 2=mk2, 3=mk3 etc.
+}
+
+// Version returns the version number of the firmware, e.g. 204 for v204.
+func (fi FirmwareInfo) Version() int {
+	return int(fi.Major*100 + fi.Minor)
+}
+
+func (fi FirmwareInfo) String() string {
+	return fmt.Sprintf("🧬{ver=v%d gen=%d}", fi.Version(), fi.Generation)
+}
+
+// Capabilities returns the set of features supported by the firmware.
+func (fi FirmwareInfo) Capabilities() Capabilities {
+	return Capabilities{
+		PerLEDAddressing: fi.Generation >= 2,
+		StartupParams:    fi.Generation >= 3 || (fi.Generation == 2 && fi.Version() >= minBootVer),
+		Notes:            fi.Generation >= 3,
+		SetRGBNowLEDBug:  fi.Generation == 2,
+		PatternLines:     getMaxPattern(fi.Generation),
+	}
+}
+
+// Capabilities represents the set of features supported by the firmware of a blink(1) device.
+type Capabilities struct {
+	PerLEDAddressing bool // Addressing LED 1 and LED 2 separately, for mk2+
+	StartupParams    bool // Setting startup (power-on) behavior, for mk2 with firmware v204+ and mk3+
+	Notes            bool // Reading and writing notes and chip ID, for mk3+
+	SetRGBNowLEDBug  bool // SetRGBNow with ledN > 0 sets all LEDs to white, for mk2
+	PatternLines     uint // Number of lines in pattern RAM, 12 for mk1 and 32 for mk2+
+}
+
+func (cp Capabilities) String() string {
+	return fmt.Sprintf("🧰{per_led=%t startup=%t notes=%t rgb_now_bug=%t lines=%d}", cp.PerLEDAddressing, cp.StartupParams, cp.Notes, cp.SetRGBNowLEDBug, cp.PatternLines)
+}
+
+// UnsupportedError is returned when a feature is not supported by the firmware of the device, instead of sending bytes the device will misinterpret.
+type UnsupportedError struct {
+	Feature  string       // Name of the unsupported feature
+	Firmware FirmwareInfo // Firmware of the device, the version is zero if it's unknown
+}
+
+func (e *UnsupportedError) Error() string {
+	if len(e.Firmware.Raw) == 0 {
+		return fmt.Sprintf("b1: %s is unsupported on mk%d", e.Feature, e.Firmware.Generation)
+	}
+	return fmt.Sprintf("b1: %s is unsupported on mk%d firmware v%d", e.Feature, e.Firmware.Generation, e.Firmware.Version())
+}
+
+// Is reports whether the target is ErrUnsupported, so errors.Is(err, ErrUnsupported) matches all UnsupportedError values.
+func (e *UnsupportedError) Is(target error) bool {
+	return target == ErrUnsupported
+}
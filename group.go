@@ -0,0 +1,314 @@
+package blink1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image/color"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	hid "github.com/b1ug/gid"
+)
+
+// GroupError is returned when the operation fails on some devices of a Group, it contains the errors keyed by serial number.
+type GroupError struct {
+	Total  int              // Number of devices the operation was performed on
+	Errors map[string]error // Serial number -> error of the failed devices
+}
+
+func (e *GroupError) Error() string {
+	sns := make([]string, 0, len(e.Errors))
+	for sn := range e.Errors {
+		sns = append(sns, sn)
+	}
+	sort.Strings(sns)
+
+	msgs := make([]string, len(sns))
+	for i, sn := range sns {
+		msgs[i] = fmt.Sprintf("%s: %v", sn, e.Errors[sn])
+	}
+	return fmt.Sprintf("b1: %d of %d devices failed: %s", len(e.Errors), e.Total, strings.Join(msgs, "; "))
+}
+
+// Is reports whether the error of any failed device matches the target.
+func (e *GroupError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Group drives multiple blink(1) devices as one, the commands are sent to all devices concurrently and the results are aggregated by serial number.
+type Group struct {
+	ctrls []*Controller // sorted by serial number
+
+	// synced pattern
+	mu        sync.Mutex
+	synced    *Pattern      // pattern loop started by PlayPatternSynced, without states
+	alignMu   sync.Mutex    // guards the auto align worker, separate from mu which the worker needs
+	alignQuit chan struct{} // closed to stop the auto align worker
+	alignDone chan struct{} // closed when the auto align worker quits
+}
+
+// NewGroup creates a group of the given controllers.
+func NewGroup(ctrls ...*Controller) *Group {
+	cs := append([]*Controller(nil), ctrls...)
+	sort.SliceStable(cs, func(i, j int) bool {
+		return cs[i].dev.sn < cs[j].dev.sn
+	})
+	return &Group{ctrls: cs}
+}
+
+// OpenGroup opens all blink(1) devices connected to the system as a group.
+// If some devices fail to open, the group of the opened ones is returned along with a GroupError.
+func OpenGroup() (*Group, error) {
+	infos := ListDeviceInfo()
+	if len(infos) == 0 {
+		return nil, ErrDeviceNotFound
+	}
+	return openGroup(infos, nil)
+}
+
+// OpenGroupBySerialNumbers opens the blink(1) devices with the given serial numbers as a group.
+// If some devices are not found or fail to open, the group of the opened ones is returned along with a GroupError.
+func OpenGroupBySerialNumbers(sns ...string) (*Group, error) {
+	infos := make([]*hid.DeviceInfo, 0, len(sns))
+	errs := make(map[string]error)
+	for _, sn := range sns {
+		di, err := FindDeviceInfoBySerialNumber(sn)
+		if err != nil {
+			errs[sn] = err
+			continue
+		}
+		infos = append(infos, di)
+	}
+	return openGroup(infos, errs)
+}
+
+// openGroup opens the devices as a group, and merges the errors with the given ones.
+func openGroup(infos []*hid.DeviceInfo, errs map[string]error) (*Group, error) {
+	if errs == nil {
+		errs = make(map[string]error)
+	}
+	total := len(infos) + len(errs)
+
+	var ctrls []*Controller
+	for _, di := range infos {
+		c, err := OpenController(di)
+		if err != nil {
+			errs[di.SerialNumber] = err
+			continue
+		}
+		ctrls = append(ctrls, c)
+	}
+
+	if len(ctrls) == 0 {
+		return nil, &GroupError{Total: total, Errors: errs}
+	}
+	g := NewGroup(ctrls...)
+	if len(errs) > 0 {
+		return g, &GroupError{Total: total, Errors: errs}
+	}
+	return g, nil
+}
+
+func (g *Group) String() string {
+	return fmt.Sprintf("👥{n=%d sn=%s}", len(g.ctrls), strings.Join(g.SerialNumbers(), ","))
+}
+
+// Len returns the number of devices in the group.
+func (g *Group) Len() int {
+	return len(g.ctrls)
+}
+
+// Controllers returns the controllers of the devices in the group, sorted by serial number.
+func (g *Group) Controllers() []*Controller {
+	return append([]*Controller(nil), g.ctrls...)
+}
+
+// SerialNumbers returns the serial numbers of the devices in the group in ascending order.
+func (g *Group) SerialNumbers() []string {
+	sns := make([]string, len(g.ctrls))
+	for i, c := range g.ctrls {
+		sns[i] = c.dev.sn
+	}
+	return sns
+}
+
+// Close stops the auto align and closes all the controllers in the group.
+func (g *Group) Close() {
+	g.StopAutoAlign()
+	_ = g.Each(context.Background(), func(_ context.Context, c *Controller) error {
+		c.Close()
+		return nil
+	})
+}
+
+// Each runs the operation on all the controllers in the group concurrently, and waits for all of them to finish.
+// It returns a GroupError with the errors of the failed devices, or nil if all succeeded.
+func (g *Group) Each(ctx context.Context, op func(ctx context.Context, c *Controller) error) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs = make(map[string]error)
+	)
+	for _, c := range g.ctrls {
+		wg.Add(1)
+		go func(c *Controller) {
+			defer wg.Done()
+			if err := op(ctx, c); err != nil {
+				mu.Lock()
+				errs[c.dev.sn] = err
+				mu.Unlock()
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return &GroupError{Total: len(g.ctrls), Errors: errs}
+	}
+	return nil
+}
+
+// SetGammaCorrection sets the gamma correction on/off for all the controllers in the group.
+func (g *Group) SetGammaCorrection(on bool) {
+	for _, c := range g.ctrls {
+		c.SetGammaCorrection(on)
+	}
+}
+
+// PlayState works like Controller.PlayState on all devices in the group.
+func (g *Group) PlayState(st LightState) error {
+	return g.PlayStateContext(context.Background(), st)
+}
+
+// PlayStateContext works like Controller.PlayStateContext on all devices in the group.
+func (g *Group) PlayStateContext(ctx context.Context, st LightState) error {
+	return g.Each(ctx, func(ctx context.Context, c *Controller) error {
+		return c.PlayStateContext(ctx, st)
+	})
+}
+
+// PlayColor works like Controller.PlayColor on all devices in the group.
+func (g *Group) PlayColor(cl color.Color) error {
+	return g.PlayColorContext(context.Background(), cl)
+}
+
+// PlayColorContext works like Controller.PlayColorContext on all devices in the group.
+func (g *Group) PlayColorContext(ctx context.Context, cl color.Color) error {
+	return g.Each(ctx, func(ctx context.Context, c *Controller) error {
+		return c.PlayColorContext(ctx, cl)
+	})
+}
+
+// PlayPattern works like Controller.PlayPattern on all devices in the group.
+func (g *Group) PlayPattern(pt Pattern) error {
+	return g.PlayPatternContext(context.Background(), pt)
+}
+
+// PlayPatternContext works like Controller.PlayPatternContext on all devices in the group.
+func (g *Group) PlayPatternContext(ctx context.Context, pt Pattern) error {
+	g.resetSynced()
+	return g.Each(ctx, func(ctx context.Context, c *Controller) error {
+		return c.PlayPatternContext(ctx, pt)
+	})
+}
+
+// LoadPattern works like Controller.LoadPattern on all devices in the group.
+func (g *Group) LoadPattern(posStart, posEnd uint, seq StateSequence) error {
+	return g.LoadPatternContext(context.Background(), posStart, posEnd, seq)
+}
+
+// LoadPatternContext works like Controller.LoadPatternContext on all devices in the group.
+func (g *Group) LoadPatternContext(ctx context.Context, posStart, posEnd uint, seq StateSequence) error {
+	return g.Each(ctx, func(ctx context.Context, c *Controller) error {
+		return c.LoadPatternContext(ctx, posStart, posEnd, seq)
+	})
+}
+
+// WritePattern works like Controller.WritePattern on all devices in the group.
+func (g *Group) WritePattern() error {
+	return g.Each(context.Background(), func(ctx context.Context, c *Controller) error {
+		return c.WritePatternContext(ctx)
+	})
+}
+
+// StopPlaying works like Controller.StopPlaying on all devices in the group.
+func (g *Group) StopPlaying() error {
+	return g.StopPlayingContext(context.Background())
+}
+
+// StopPlayingContext works like Controller.StopPlayingContext on all devices in the group.
+func (g *Group) StopPlayingContext(ctx context.Context) error {
+	g.resetSynced()
+	return g.Each(ctx, func(ctx context.Context, c *Controller) error {
+		return c.StopPlayingContext(ctx)
+	})
+}
+
+// SimpleTickle works like Controller.SimpleTickle on all devices in the group.
+func (g *Group) SimpleTickle(posStart, posEnd uint, timeout time.Duration, keepOld bool) error {
+	return g.Each(context.Background(), func(ctx context.Context, c *Controller) error {
+		return c.SimpleTickleContext(ctx, posStart, posEnd, timeout, keepOld)
+	})
+}
+
+// StartAutoTickle works like Controller.StartAutoTickle on all devices in the group.
+func (g *Group) StartAutoTickle(posStart, posEnd uint, keepOld bool) error {
+	return g.Each(context.Background(), func(ctx context.Context, c *Controller) error {
+		return c.StartAutoTickleContext(ctx, posStart, posEnd, keepOld)
+	})
+}
+
+// StopAutoTickle works like Controller.StopAutoTickle on all devices in the group.
+func (g *Group) StopAutoTickle() {
+	_ = g.Each(context.Background(), func(_ context.Context, c *Controller) error {
+		c.StopAutoTickle()
+		return nil
+	})
+}
+
+// StartManualTickle works like Controller.StartManualTickle on all devices in the group, the signals sent to the returned channel are forwarded to all devices.
+// If it fails on some devices, the manual tickle on the others is stopped. Signals to devices closed later are discarded,
+// and the signals are accepted until the returned channel is closed, even if all devices are closed.
+//
+// To stop the manual tickle, close the returned channel.
+func (g *Group) StartManualTickle(posStart, posEnd uint, timeout time.Duration, keepOld bool) (chan<- struct{}, error) {
+	var mu sync.Mutex
+	members := make([]chan<- struct{}, 0, len(g.ctrls))
+	if err := g.Each(context.Background(), func(ctx context.Context, c *Controller) error {
+		ch, err := c.StartManualTickleContext(ctx, posStart, posEnd, timeout, keepOld)
+		if err == nil {
+			mu.Lock()
+			members = append(members, ch)
+			mu.Unlock()
+		}
+		return err
+	}); err != nil {
+		for _, ch := range members {
+			close(ch)
+		}
+		return nil, err
+	}
+
+	// forward signals to all devices, the ones of closed controllers are never blocked as they're drained
+	tickCh := make(chan struct{})
+	go func() {
+		for range tickCh {
+			for _, ch := range members {
+				ch <- struct{}{}
+			}
+		}
+		// quit when tickCh is closed
+		for _, ch := range members {
+			close(ch)
+		}
+	}()
+	return tickCh, nil
+}
@@ -0,0 +1,196 @@
+package blink1
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PlayPatternSynced works like PlayPattern, but starts playing the pattern on all devices in the group at the same time.
+// The pattern is uploaded to all devices first, then the play commands are fired together right after a barrier, so the loops start within a few milliseconds.
+// The pattern is kept for re-aligning by AlignPattern and StartAutoAlign.
+func (g *Group) PlayPatternSynced(ctx context.Context, pt Pattern) error {
+	// ensure range is valid for all devices
+	for _, c := range g.ctrls {
+		if !c.isPosRangeValid(pt.StartPosition, pt.EndPosition) {
+			return ErrInvalidPosition
+		}
+	}
+	if pt.RepeatTimes > maxRepeat {
+		return ErrInvalidRepeatTimes
+	}
+
+	// upload pattern to all devices
+	if err := g.LoadPatternContext(ctx, pt.StartPosition, pt.EndPosition, pt.Sequence); err != nil {
+		return err
+	}
+
+	// fire play commands together
+	loop := Pattern{StartPosition: pt.StartPosition, EndPosition: pt.EndPosition, RepeatTimes: pt.RepeatTimes}
+	g.mu.Lock()
+	g.synced = &loop
+	g.mu.Unlock()
+	return g.firePlay(ctx, loop)
+}
+
+// AlignPattern re-aligns the pattern started by PlayPatternSynced, if the devices are found drifted apart by their current positions of play state.
+// It returns true if the pattern is restarted on all devices at the same time.
+// For a pattern with finite repeat times, it's considered completed once any device stops playing, and it will not be re-aligned any more.
+func (g *Group) AlignPattern(ctx context.Context) (bool, error) {
+	g.mu.Lock()
+	loop := g.synced
+	g.mu.Unlock()
+	if loop == nil {
+		return false, nil
+	}
+
+	// read play states of all devices
+	var mu sync.Mutex
+	states := make(map[string]DevicePatternState, len(g.ctrls))
+	if err := g.Each(ctx, func(ctx context.Context, c *Controller) error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.settleCoalesced()
+		st, err := c.dev.ReadPlaystateContext(ctx)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		states[c.dev.sn] = st
+		mu.Unlock()
+		return nil
+	}); err != nil {
+		return false, err
+	}
+
+	// skip the finite loop once completed, since the devices may finish slightly apart
+	if loop.RepeatTimes > 0 {
+		for _, c := range g.ctrls {
+			if !states[c.dev.sn].IsPlaying {
+				g.mu.Lock()
+				if g.synced == loop {
+					g.synced = nil
+				}
+				g.mu.Unlock()
+				return false, nil
+			}
+		}
+	}
+
+	// check if all devices are playing at the same position, or all stopped
+	var (
+		first   DevicePatternState
+		aligned = true
+	)
+	for i, c := range g.ctrls {
+		st := states[c.dev.sn]
+		if i == 0 {
+			first = st
+		} else if st.IsPlaying != first.IsPlaying || (st.IsPlaying && st.CurrentPos != first.CurrentPos) {
+			aligned = false
+			break
+		}
+	}
+	if aligned {
+		return false, nil
+	}
+	return true, g.firePlay(ctx, *loop)
+}
+
+// StartAutoAlign re-aligns the pattern started by PlayPatternSynced with AlignPattern periodically at the given interval.
+// If the auto align is already started, it will be stopped and restarted. Errors of re-aligning are ignored.
+// It returns ErrInvalidArgument if the interval is not positive.
+//
+// To stop the auto align, call StopAutoAlign().
+func (g *Group) StartAutoAlign(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("%w: align interval %v", ErrInvalidArgument, interval)
+	}
+
+	g.alignMu.Lock()
+	defer g.alignMu.Unlock()
+
+	// if already started, stop it first
+	g.stopAutoAlign()
+
+	quitCh, doneCh := make(chan struct{}), make(chan struct{})
+	g.alignQuit, g.alignDone = quitCh, doneCh
+	go func() {
+		defer close(doneCh)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			<-quitCh
+			cancel()
+		}()
+		for {
+			select {
+			case <-ticker.C:
+				_, _ = g.AlignPattern(ctx)
+			case <-quitCh:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// StopAutoAlign stops the auto align and waits for it to quit. It does nothing if the auto align is not started.
+func (g *Group) StopAutoAlign() {
+	g.alignMu.Lock()
+	defer g.alignMu.Unlock()
+
+	g.stopAutoAlign()
+}
+
+// stopAutoAlign stops the auto align worker if it's started and waits for it to quit.
+func (g *Group) stopAutoAlign() {
+	if g.alignQuit != nil {
+		close(g.alignQuit)
+		<-g.alignDone
+		g.alignQuit, g.alignDone = nil, nil
+	}
+}
+
+// resetSynced drops the pattern started by PlayPatternSynced, so it will not be re-aligned any more.
+func (g *Group) resetSynced() {
+	g.mu.Lock()
+	g.synced = nil
+	g.mu.Unlock()
+}
+
+// firePlay sends the play commands of the pattern loop to all devices at the same time.
+// Each controller is locked and ready before the barrier, so the commands are sent back-to-back once all are ready.
+func (g *Group) firePlay(ctx context.Context, loop Pattern) error {
+	var ready sync.WaitGroup
+	ready.Add(len(g.ctrls))
+	startCh := make(chan struct{})
+	go func() {
+		ready.Wait()
+		close(startCh)
+	}()
+
+	return g.Each(ctx, func(ctx context.Context, c *Controller) error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.settleCoalesced()
+
+		// wait for the others
+		ready.Done()
+		select {
+		case <-startCh:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		end := loop.EndPosition
+		if end == 0 {
+			end = getMaxPattern(c.dev.gen) - 1
+		}
+		return c.dev.PlayLoopContext(ctx, true, loop.StartPosition, end, loop.RepeatTimes)
+	})
+}
@@ -0,0 +1,206 @@
+package blink1_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	b1 "github.com/b1ug/blink1-go"
+)
+
+func TestGroup(t *testing.T) {
+	var (
+		emus  []*b1.Emulator
+		ctrls []*b1.Controller
+	)
+	for _, sn := range []string{"EMU00003", "EMU00001", "EMU00002"} {
+		emu := b1.NewEmulator(2, sn)
+		c, err := b1.NewControllerWithTransport(emu)
+		if err != nil {
+			t.Fatalf("NewControllerWithTransport() got unexpected error: %v", err)
+		}
+		emus = append(emus, emu)
+		ctrls = append(ctrls, c)
+	}
+	g := b1.NewGroup(ctrls...)
+	defer g.Close()
+	g.SetGammaCorrection(false)
+
+	if g.Len() != 3 || g.String() != "👥{n=3 sn=EMU00001,EMU00002,EMU00003}" {
+		t.Errorf("NewGroup() = %v, want 3 devices sorted by serial number", g)
+	}
+
+	// fan out to all devices
+	if err := g.PlayColor(b1.ColorRed); err != nil {
+		t.Fatalf("PlayColor() got unexpected error: %v", err)
+	}
+	for _, c := range g.Controllers() {
+		if cl, _ := c.ReadColor(b1.LED1); b1.ColorToHex(cl) != "#FF0000" {
+			t.Errorf("ReadColor() of %v = %v, want #FF0000", c, b1.ColorToHex(cl))
+		}
+	}
+	st := b1.NewLightState(b1.ColorBlue, 0, b1.LEDAll)
+	if err := g.LoadPattern(0, 1, b1.StateSequence{st, st}); err != nil {
+		t.Fatalf("LoadPattern() got unexpected error: %v", err)
+	}
+	if err := g.PlayPattern(b1.Pattern{StartPosition: 0, EndPosition: 1}); err != nil {
+		t.Fatalf("PlayPattern() got unexpected error: %v", err)
+	}
+	if err := g.StopPlaying(); err != nil {
+		t.Fatalf("StopPlaying() got unexpected error: %v", err)
+	}
+
+	// partial failure
+	emus[0].Unplug()
+	err := g.PlayColor(b1.ColorGreen)
+	var ge *b1.GroupError
+	if !errors.As(err, &ge) || ge.Total != 3 || len(ge.Errors) != 1 || ge.Errors["EMU00003"] == nil {
+		t.Fatalf("PlayColor() with an unplugged device got error: %v, want GroupError of EMU00003", err)
+	}
+	if !errors.Is(err, b1.ErrDisconnected) {
+		t.Errorf("PlayColor() with an unplugged device got error: %v, want ErrDisconnected", err)
+	}
+	if cl, _ := ctrls[1].ReadColor(b1.LED1); b1.ColorToHex(cl) != "#00FF00" {
+		t.Errorf("ReadColor() of %v = %v, want #00FF00", ctrls[1], b1.ColorToHex(cl))
+	}
+}
+
+func TestOpenGroupBySerialNumbers_NotFound(t *testing.T) {
+	g, err := b1.OpenGroupBySerialNumbers("NOTEXIST1", "NOTEXIST2")
+	if g != nil {
+		t.Errorf("OpenGroupBySerialNumbers() = %v, want nil", g)
+	}
+	var ge *b1.GroupError
+	if !errors.As(err, &ge) || ge.Total != 2 || !errors.Is(err, b1.ErrDeviceNotFound) {
+		t.Errorf("OpenGroupBySerialNumbers() got error: %v, want GroupError of ErrDeviceNotFound", err)
+	}
+}
+
+func TestGroup_StartManualTickle(t *testing.T) {
+	var ctrls []*b1.Controller
+	for _, sn := range []string{"EMU00001", "EMU00002", "EMU00003"} {
+		c, err := b1.NewControllerWithTransport(b1.NewEmulator(2, sn))
+		if err != nil {
+			t.Fatalf("NewControllerWithTransport() got unexpected error: %v", err)
+		}
+		ctrls = append(ctrls, c)
+	}
+	g := b1.NewGroup(ctrls...)
+	defer g.Close()
+
+	base := runtime.NumGoroutine()
+	tickCh, err := g.StartManualTickle(0, 1, time.Second, false)
+	if err != nil {
+		t.Fatalf("StartManualTickle() got unexpected error: %v", err)
+	}
+	tickCh <- struct{}{}
+
+	// closing a member must not block the others
+	ctrls[1].Close()
+	for i := 0; i < 5; i++ {
+		select {
+		case tickCh <- struct{}{}:
+		case <-time.After(time.Second):
+			t.Fatalf("tick #%d after closing a member got blocked", i+1)
+		}
+	}
+	close(tickCh)
+
+	// all workers and drainers quit, including the ones of the closed member
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > base && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > base {
+		t.Errorf("goroutines after stopping manual tickle = %d, want <= %d", n, base)
+	}
+}
+
+func TestGroup_PlayPatternSynced(t *testing.T) {
+	var ctrls []*b1.Controller
+	for _, sn := range []string{"EMU00001", "EMU00002", "EMU00003"} {
+		c, err := b1.NewControllerWithTransport(b1.NewEmulator(2, sn))
+		if err != nil {
+			t.Fatalf("NewControllerWithTransport() got unexpected error: %v", err)
+		}
+		ctrls = append(ctrls, c)
+	}
+	g := b1.NewGroup(ctrls...)
+	defer g.Close()
+
+	// nothing to align before synced play
+	if ok, err := g.AlignPattern(context.Background()); ok || err != nil {
+		t.Errorf("AlignPattern() before synced play = %v, %v, want false", ok, err)
+	}
+
+	st := b1.NewLightState(b1.ColorBlue, 200*time.Millisecond, b1.LEDAll)
+	pt := b1.Pattern{StartPosition: 0, EndPosition: 1, Sequence: b1.StateSequence{st, st}}
+	if err := g.PlayPatternSynced(context.Background(), b1.Pattern{StartPosition: 2, EndPosition: 1}); !errors.Is(err, b1.ErrInvalidPosition) {
+		t.Errorf("PlayPatternSynced() with invalid range got error: %v, want ErrInvalidPosition", err)
+	}
+	if err := g.PlayPatternSynced(context.Background(), pt); err != nil {
+		t.Fatalf("PlayPatternSynced() got unexpected error: %v", err)
+	}
+	if ok, err := g.AlignPattern(context.Background()); ok || err != nil {
+		t.Errorf("AlignPattern() after synced play = %v, %v, want false", ok, err)
+	}
+
+	// drift one device apart, and re-align
+	if err := ctrls[1].StopPlaying(); err != nil {
+		t.Fatalf("StopPlaying() got unexpected error: %v", err)
+	}
+	if ok, err := g.AlignPattern(context.Background()); !ok || err != nil {
+		t.Errorf("AlignPattern() after drift = %v, %v, want true", ok, err)
+	}
+	var pos []uint
+	for _, c := range ctrls {
+		ps, err := c.GetPatternState()
+		if err != nil || !ps.IsPlaying {
+			t.Errorf("GetPatternState() of %v after re-align = %v, %v, want playing", c, ps, err)
+		}
+		pos = append(pos, ps.CurrentPosition)
+	}
+	if pos[0] != pos[1] || pos[1] != pos[2] {
+		t.Errorf("current positions after re-align = %v, want the same", pos)
+	}
+
+	// auto align
+	_ = ctrls[2].StopPlaying()
+	if err := g.StartAutoAlign(0); !errors.Is(err, b1.ErrInvalidArgument) {
+		t.Errorf("StartAutoAlign(0) got error: %v, want ErrInvalidArgument", err)
+	}
+	if err := g.StartAutoAlign(20 * time.Millisecond); err != nil {
+		t.Fatalf("StartAutoAlign() got unexpected error: %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	g.StopAutoAlign()
+	g.StopAutoAlign()
+	if ok, _ := ctrls[2].IsPatternPlaying(); !ok {
+		t.Errorf("IsPatternPlaying() of %v after auto align = false, want true", ctrls[2])
+	}
+
+	// stopped pattern is not re-aligned
+	if err := g.StopPlaying(); err != nil {
+		t.Fatalf("StopPlaying() got unexpected error: %v", err)
+	}
+	_ = ctrls[0].PlayPattern(b1.Pattern{StartPosition: 0, EndPosition: 1})
+	if ok, err := g.AlignPattern(context.Background()); ok || err != nil {
+		t.Errorf("AlignPattern() after group stop = %v, %v, want false", ok, err)
+	}
+	_ = ctrls[0].StopPlaying()
+
+	// completed finite loop is not re-aligned
+	pt.RepeatTimes = 1
+	if err := g.PlayPatternSynced(context.Background(), pt); err != nil {
+		t.Fatalf("PlayPatternSynced() got unexpected error: %v", err)
+	}
+	_ = ctrls[1].StopPlaying()
+	if ok, err := g.AlignPattern(context.Background()); ok || err != nil {
+		t.Errorf("AlignPattern() after finite loop completed = %v, %v, want false", ok, err)
+	}
+	if ok, _ := ctrls[1].IsPatternPlaying(); ok {
+		t.Errorf("IsPatternPlaying() of %v after finite loop completed = true, want false", ctrls[1])
+	}
+}
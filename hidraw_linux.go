@@ -0,0 +1,285 @@
+//go:build linux && (386 || amd64 || arm || arm64 || riscv64 || s390x)
+// +build linux
+// +build 386 amd64 arm arm64 riscv64 s390x
+
+package blink1
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	hid "github.com/b1ug/gid"
+)
+
+// HidrawIoctl performs the ioctl request with the buffer as argument on the opened hidraw device file, and returns the non-negative result of the syscall.
+type HidrawIoctl func(f *os.File, req uint, buf []byte) (int, error)
+
+// Hidraw is a pure-Go backend of blink(1) devices on Linux, it enumerates the hidraw class in sysfs and exchanges feature reports with HIDIOCSFEATURE/HIDIOCGFEATURE ioctls on /dev/hidrawN,
+// so neither Cgo nor libusb is required. The devices opened by it work the same as the ones opened by OpenDevice() and OpenController().
+//
+// The hidraw device files are usually accessible by root only, a udev rule is required to grant access to the other users.
+type Hidraw struct {
+	mu      sync.Mutex
+	sysRoot string // directory of hidraw class in sysfs
+	devRoot string // directory of hidraw device files
+	ioctl   HidrawIoctl
+}
+
+// NewHidraw creates a hidraw backend with the system paths, i.e. /sys/class/hidraw and /dev.
+func NewHidraw() *Hidraw {
+	return &Hidraw{
+		sysRoot: "/sys/class/hidraw",
+		devRoot: "/dev",
+		ioctl:   sysIoctl,
+	}
+}
+
+// SetRoots sets the directory of hidraw class in sysfs and the directory of hidraw device files, it's useful for testing against a fake sysfs tree.
+func (h *Hidraw) SetRoots(sysRoot, devRoot string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sysRoot, h.devRoot = sysRoot, devRoot
+}
+
+// SetIoctl sets the function to perform ioctl requests on the device files, it's the ioctl syscall by default.
+func (h *Hidraw) SetIoctl(ioctl HidrawIoctl) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ioctl = ioctl
+}
+
+// ListDeviceInfo returns the HID device info of all blink(1) devices found in sysfs, sorted by serial number like ListDeviceInfo().
+// It can be used as the source of Watcher, Enumerator and Registry.
+func (h *Hidraw) ListDeviceInfo() []*hid.DeviceInfo {
+	h.mu.Lock()
+	sysRoot, devRoot := h.sysRoot, h.devRoot
+	h.mu.Unlock()
+
+	ents, err := ioutil.ReadDir(sysRoot)
+	if err != nil {
+		return nil
+	}
+	var infos []*hid.DeviceInfo
+	for _, ent := range ents {
+		di, err := parseHidrawSysfs(filepath.Join(sysRoot, ent.Name()), filepath.Join(devRoot, ent.Name()))
+		if err != nil || !IsBlink1Device(di) {
+			continue
+		}
+		infos = append(infos, di)
+	}
+	return sortDeviceInfo(infos)
+}
+
+// Open opens the hidraw device file of the blink(1) device as a transport.
+func (h *Hidraw) Open(info *hid.DeviceInfo) (Transport, error) {
+	if info == nil {
+		return nil, errNilDeviceInfo
+	}
+	if !IsBlink1Device(info) {
+		return nil, ErrNotBlink1
+	}
+
+	h.mu.Lock()
+	ioctl := h.ioctl
+	h.mu.Unlock()
+
+	f, err := os.OpenFile(info.Path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &hidrawTransport{h: h, info: info, f: f, ioctl: ioctl}, nil
+}
+
+// OpenDevice opens the blink(1) device with hidraw and returns as device.
+func (h *Hidraw) OpenDevice(info *hid.DeviceInfo) (*Device, error) {
+	tr, err := h.Open(info)
+	if err != nil {
+		return nil, err
+	}
+	return NewDevice(tr)
+}
+
+// OpenController opens the blink(1) device with hidraw and returns as controller.
+func (h *Hidraw) OpenController(info *hid.DeviceInfo) (*Controller, error) {
+	dev, err := h.OpenDevice(info)
+	if err != nil {
+		return nil, err
+	}
+	return newController(dev), nil
+}
+
+// OpenNextDevice opens the first blink(1) device found in sysfs and returns as device.
+func (h *Hidraw) OpenNextDevice() (*Device, error) {
+	infos := h.ListDeviceInfo()
+	if len(infos) == 0 {
+		return nil, ErrDeviceNotFound
+	}
+	return h.OpenDevice(infos[0])
+}
+
+// hidrawTransport is the Transport backed by a hidraw device file.
+type hidrawTransport struct {
+	h     *Hidraw // backend which opened it, for reopening
+	info  *hid.DeviceInfo
+	f     *os.File
+	ioctl HidrawIoctl
+}
+
+func (t *hidrawTransport) WriteFeature(buf []byte) error {
+	_, err := t.ioctl(t.f, hidiocSFeature(len(buf)), buf)
+	return t.classify(err)
+}
+
+func (t *hidrawTransport) ReadFeature(buf []byte) (int, error) {
+	n, err := t.ioctl(t.f, hidiocGFeature(len(buf)), buf)
+	return n, t.classify(err)
+}
+
+func (t *hidrawTransport) Close() {
+	_ = t.f.Close()
+}
+
+func (t *hidrawTransport) GetDeviceInfo() *hid.DeviceInfo {
+	return t.info
+}
+
+// reopen rescans the hidraw devices for the one with the same serial number and opens it, so the device stays on hidraw after it's re-plugged in resilient mode.
+func (t *hidrawTransport) reopen() (Transport, error) {
+	for _, info := range t.h.ListDeviceInfo() {
+		if info.SerialNumber == t.info.SerialNumber {
+			return t.h.Open(info)
+		}
+	}
+	return nil, ErrDeviceNotFound
+}
+
+// classify wraps the I/O error as ErrDisconnected if the device file is gone.
+func (t *hidrawTransport) classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, syscall.ENODEV) || errors.Is(err, syscall.ENXIO) {
+		return fmt.Errorf("%w: %v", ErrDisconnected, err)
+	}
+	if _, le := os.Stat(t.info.Path); le != nil {
+		return fmt.Errorf("%w: %v", ErrDisconnected, err)
+	}
+	return err
+}
+
+// hidiocSFeature returns the HIDIOCSFEATURE(len) ioctl request, i.e. _IOC(_IOC_WRITE|_IOC_READ, 'H', 0x06, len) in the generic encoding of Linux.
+func hidiocSFeature(n int) uint {
+	return hidIoc(0x06, n)
+}
+
+// hidiocGFeature returns the HIDIOCGFEATURE(len) ioctl request, i.e. _IOC(_IOC_WRITE|_IOC_READ, 'H', 0x07, len) in the generic encoding of Linux.
+func hidiocGFeature(n int) uint {
+	return hidIoc(0x07, n)
+}
+
+// hidIoc encodes the read-write ioctl request of hidraw with the number and argument size.
+// The generic _IOC layout is only used by the architectures this file is built for, others like mips, ppc64 and sparc differ in the direction bits.
+func hidIoc(nr, n int) uint {
+	const iocReadWrite = 3
+	return iocReadWrite<<30 | uint(n)<<16 | uint('H')<<8 | uint(nr)
+}
+
+// sysIoctl performs the ioctl syscall with the buffer as argument.
+func sysIoctl(f *os.File, req uint, buf []byte) (int, error) {
+	if len(buf) == 0 {
+		return 0, syscall.EINVAL
+	}
+	r, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(req), uintptr(unsafe.Pointer(&buf[0])))
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(r), nil
+}
+
+// parseHidrawSysfs parses the HID device info of the hidraw node from its directory in sysfs.
+// The HID attributes are read from device/uevent, and the USB attributes are read from the USB device two levels above the HID device.
+func parseHidrawSysfs(sysDir, devPath string) (*hid.DeviceInfo, error) {
+	hidDir, err := filepath.EvalSymlinks(filepath.Join(sysDir, "device"))
+	if err != nil {
+		return nil, err
+	}
+	uevent, err := parseUevent(filepath.Join(hidDir, "uevent"))
+	if err != nil {
+		return nil, err
+	}
+
+	// HID_ID=0003:000027B8:000001ED for bus:vendor:product
+	parts := strings.Split(uevent["HID_ID"], ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("b1: invalid HID_ID %q in %s", uevent["HID_ID"], hidDir)
+	}
+	vid, err := strconv.ParseUint(parts[1], 16, 32)
+	if err != nil {
+		return nil, err
+	}
+	pid, err := strconv.ParseUint(parts[2], 16, 32)
+	if err != nil {
+		return nil, err
+	}
+	di := &hid.DeviceInfo{
+		Path:         devPath,
+		VendorID:     uint16(vid),
+		ProductID:    uint16(pid),
+		Product:      uevent["HID_NAME"],
+		SerialNumber: uevent["HID_UNIQ"],
+	}
+
+	// USB device: hid -> interface -> device
+	usbDir := filepath.Dir(filepath.Dir(hidDir))
+	if s := readSysfsAttr(usbDir, "bcdDevice"); s != "" {
+		if ver, err := strconv.ParseUint(s, 16, 16); err == nil {
+			di.VersionNumber = uint16(ver)
+		}
+	}
+	if s := readSysfsAttr(usbDir, "manufacturer"); s != "" {
+		di.Manufacturer = s
+	}
+	if s := readSysfsAttr(usbDir, "product"); s != "" {
+		di.Product = s
+	}
+	if s := readSysfsAttr(usbDir, "serial"); s != "" && di.SerialNumber == "" {
+		di.SerialNumber = s
+	}
+	return di, nil
+}
+
+// parseUevent parses the KEY=VALUE lines of the uevent file.
+func parseUevent(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	kv := make(map[string]string)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if i := strings.IndexByte(sc.Text(), '='); i > 0 {
+			kv[sc.Text()[:i]] = sc.Text()[i+1:]
+		}
+	}
+	return kv, sc.Err()
+}
+
+// readSysfsAttr returns the trimmed content of the attribute file, or empty if it's not readable.
+func readSysfsAttr(dir, name string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,153 @@
+//go:build linux && (386 || amd64 || arm || arm64 || riscv64 || s390x)
+// +build linux
+// +build 386 amd64 arm arm64 riscv64 s390x
+
+package blink1_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	b1 "github.com/b1ug/blink1-go"
+)
+
+// fakeHidrawTree creates a fake sysfs tree of hidraw class and device files under the directory.
+func fakeHidrawTree(t *testing.T, dir string, nodes map[string]string, bcd map[string]string) (string, string) {
+	sysRoot := filepath.Join(dir, "sys", "class", "hidraw")
+	devRoot := filepath.Join(dir, "dev")
+	for _, d := range []string{sysRoot, devRoot} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf("MkdirAll() got unexpected error: %v", err)
+		}
+	}
+	for node, uevent := range nodes {
+		usbDir := filepath.Join(dir, "sys", "devices", "usb1", node)
+		hidDir := filepath.Join(usbDir, node+":1.0", "0003:"+node)
+		if err := os.MkdirAll(hidDir, 0755); err != nil {
+			t.Fatalf("MkdirAll() got unexpected error: %v", err)
+		}
+		files := map[string]string{
+			filepath.Join(hidDir, "uevent"):       uevent,
+			filepath.Join(usbDir, "bcdDevice"):    bcd[node] + "\n",
+			filepath.Join(usbDir, "manufacturer"): "ThingM\n",
+			filepath.Join(devRoot, node):          "",
+			filepath.Join(sysRoot, node, "dev"):   "247:0\n",
+		}
+		for path, data := range files {
+			_ = os.MkdirAll(filepath.Dir(path), 0755)
+			if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+				t.Fatalf("WriteFile() got unexpected error: %v", err)
+			}
+		}
+		if err := os.Symlink(hidDir, filepath.Join(sysRoot, node, "device")); err != nil {
+			t.Fatalf("Symlink() got unexpected error: %v", err)
+		}
+	}
+	return sysRoot, devRoot
+}
+
+func TestHidraw(t *testing.T) {
+	dir, err := ioutil.TempDir("", "b1-hidraw")
+	if err != nil {
+		t.Fatalf("TempDir() got unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	sysRoot, devRoot := fakeHidrawTree(t, dir, map[string]string{
+		"hidraw0": "DRIVER=hid-generic\nHID_ID=0003:000027B8:000001ED\nHID_NAME=ThingM blink(1) mk3\nHID_UNIQ=3000C001\n",
+		"hidraw1": "DRIVER=hid-generic\nHID_ID=0003:0000046D:0000C52B\nHID_NAME=Logitech USB Receiver\nHID_UNIQ=\n",
+		"hidraw2": "DRIVER=hid-generic\nHID_ID=0003:000027B8:000001ED\nHID_NAME=ThingM blink(1) mk2\nHID_UNIQ=2000B001\n",
+	}, map[string]string{"hidraw0": "0003", "hidraw1": "1211", "hidraw2": "0002"})
+
+	// stub ioctl layer routes the feature reports to emulators by device file
+	emus := map[string]*b1.Emulator{
+		filepath.Join(devRoot, "hidraw0"): b1.NewEmulator(3, "3000C001"),
+		filepath.Join(devRoot, "hidraw2"): b1.NewEmulator(2, "2000B001"),
+	}
+	var reqs []uint
+	h := b1.NewHidraw()
+	h.SetRoots(sysRoot, devRoot)
+	h.SetIoctl(func(f *os.File, req uint, buf []byte) (int, error) {
+		reqs = append(reqs, req)
+		e, ok := emus[f.Name()]
+		if !ok {
+			return 0, syscall.ENODEV
+		}
+		switch req & 0xff {
+		case 0x06:
+			return len(buf), e.WriteFeature(buf)
+		case 0x07:
+			return e.ReadFeature(buf)
+		}
+		return 0, syscall.EINVAL
+	})
+
+	// enumerate
+	infos := h.ListDeviceInfo()
+	if len(infos) != 2 {
+		t.Fatalf("ListDeviceInfo() got %d devices, want 2", len(infos))
+	}
+	if di := infos[0]; di.SerialNumber != "2000B001" || di.VersionNumber != 2 || di.Path != filepath.Join(devRoot, "hidraw2") || di.Manufacturer != "ThingM" {
+		t.Errorf("ListDeviceInfo()[0] got %+v, want mk2 2000B001 on hidraw2", di)
+	}
+	if di := infos[1]; di.SerialNumber != "3000C001" || di.VersionNumber != 3 || di.Product != "ThingM blink(1) mk3" {
+		t.Errorf("ListDeviceInfo()[1] got %+v, want mk3 3000C001", di)
+	}
+
+	// drop-in device
+	d, err := h.OpenDevice(infos[1])
+	if err != nil {
+		t.Fatalf("OpenDevice() got unexpected error: %v", err)
+	}
+	defer d.Close()
+	if d.GetGeneration() != 3 || d.GetSerialNumber() != "3000C001" {
+		t.Errorf("OpenDevice() got %v, want mk3 3000C001", d)
+	}
+	if ver, err := d.GetVersion(); err != nil || ver != 304 {
+		t.Errorf("GetVersion() got %d, %v, want 304", ver, err)
+	}
+	if err := d.SetRGBNow(0x12, 0x34, 0x56, b1.LED1); err != nil {
+		t.Fatalf("SetRGBNow() got unexpected error: %v", err)
+	}
+	if r, g, b, err := d.ReadRGB(b1.LED1); err != nil || r != 0x12 || g != 0x34 || b != 0x56 {
+		t.Errorf("ReadRGB() got #%02X%02X%02X, %v, want #123456", r, g, b, err)
+	}
+	if len(reqs) == 0 || reqs[0] != 0xC0094806 {
+		t.Errorf("first ioctl request got %#X, want HIDIOCSFEATURE(9) = 0xC0094806", reqs)
+	}
+
+	// controller
+	c, err := h.OpenController(infos[0])
+	if err != nil {
+		t.Fatalf("OpenController() got unexpected error: %v", err)
+	}
+	defer c.Close()
+	if err := c.PlayColor(b1.ColorBlue); err != nil {
+		t.Errorf("PlayColor() got unexpected error: %v", err)
+	}
+
+	// unplugged
+	d.SetAutoReconnect(true)
+	delete(emus, filepath.Join(devRoot, "hidraw0"))
+	_ = os.Remove(filepath.Join(devRoot, "hidraw0"))
+	if _, err := d.GetVersion(); !errors.Is(err, b1.ErrDisconnected) {
+		t.Errorf("GetVersion() on removed device got error: %v, want ErrDisconnected", err)
+	}
+
+	// re-plugged: reconnected through hidraw
+	if err := ioutil.WriteFile(filepath.Join(devRoot, "hidraw0"), nil, 0644); err != nil {
+		t.Fatalf("WriteFile() got unexpected error: %v", err)
+	}
+	emus[filepath.Join(devRoot, "hidraw0")] = b1.NewEmulator(3, "3000C001")
+	n := len(reqs)
+	if ver, err := d.GetVersion(); err != nil || ver != 304 {
+		t.Errorf("GetVersion() after re-plug got %d, %v, want 304", ver, err)
+	}
+	if len(reqs) == n {
+		t.Errorf("GetVersion() after re-plug sent no ioctl requests, want reconnected through hidraw")
+	}
+}
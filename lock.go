@@ -0,0 +1,183 @@
+package blink1
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	hid "github.com/b1ug/gid"
+)
+
+// lockPollInterval is the interval of retrying to take a lock held by others.
+const lockPollInterval = 20 * time.Millisecond
+
+// LockedError is returned when the device is locked by another process or handle, it matches ErrLocked.
+type LockedError struct {
+	SerialNumber string // Serial number of the locked device
+	PID          int    // Process ID of the holder, 0 if it's unknown
+	Path         string // Path of the lock file
+}
+
+func (e *LockedError) Error() string {
+	if e.PID <= 0 {
+		return fmt.Sprintf("b1: device %s is locked by another process", e.SerialNumber)
+	}
+	return fmt.Sprintf("b1: device %s is locked by pid %d", e.SerialNumber, e.PID)
+}
+
+// Is reports whether the target is ErrLocked, so errors.Is(err, ErrLocked) matches all LockedError values.
+func (e *LockedError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+// DeviceLock is an advisory exclusive lock of a blink(1) device keyed by serial number, which is shared by all processes of the user via a lock file.
+// It's opt-in, i.e. devices opened without locking are not prevented from accessing.
+type DeviceLock struct {
+	mu   sync.Mutex
+	sn   string
+	path string
+	f    *os.File // nil if unlocked
+}
+
+// LockDir returns the directory of the lock files, i.e. blink1 under $XDG_RUNTIME_DIR, or a per-user directory under the temporary directory if it's not set.
+func LockDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "blink1")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("blink1-%d", os.Getuid()))
+}
+
+// LockDevice takes the exclusive lock of the device with the given serial number.
+// If the wait is not positive, it fails immediately when the lock is held by others, otherwise it retries until the wait is over.
+// A LockedError with the PID of the holder is returned if the lock is not taken.
+func LockDevice(sn string, wait time.Duration) (*DeviceLock, error) {
+	return LockDeviceContext(context.Background(), sn, wait)
+}
+
+// LockDeviceContext works like LockDevice with the given context, the error of the context is returned if it's done while waiting.
+func LockDeviceContext(ctx context.Context, sn string, wait time.Duration) (*DeviceLock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	dir := LockDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, lockFileName(sn))
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		ok, err := tryLockFile(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("b1: failed to lock %s: %w", path, err)
+		}
+		if ok {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			_ = f.Close()
+			return nil, &LockedError{SerialNumber: sn, PID: readLockPID(path), Path: path}
+		}
+		if err := sleepContext(ctx, lockPollInterval); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+
+	// record the holder
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &DeviceLock{sn: sn, path: path, f: f}, nil
+}
+
+func (l *DeviceLock) String() string {
+	return fmt.Sprintf("🔒{sn=%s path=%s}", l.sn, l.path)
+}
+
+// Unlock releases the lock. It's safe to call Unlock multiple times.
+func (l *DeviceLock) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+
+	// the lock file is kept, removing it would race with other processes waiting on it
+	_ = l.f.Truncate(0)
+	err := unlockFile(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
+
+// OpenDeviceLocked takes the exclusive lock of the blink(1) device like LockDevice, and opens it as device. The lock is released when the device is closed.
+func OpenDeviceLocked(info *hid.DeviceInfo, wait time.Duration) (*Device, error) {
+	if info == nil {
+		return nil, errNilDeviceInfo
+	}
+	if !IsBlink1Device(info) {
+		return nil, ErrNotBlink1
+	}
+
+	// lock
+	l, err := LockDevice(info.SerialNumber, wait)
+	if err != nil {
+		return nil, err
+	}
+
+	// open
+	d, err := OpenDevice(info)
+	if err != nil {
+		_ = l.Unlock()
+		return nil, err
+	}
+	d.lock = l
+	return d, nil
+}
+
+// OpenControllerLocked takes the exclusive lock of the blink(1) device like LockDevice, and opens it as controller. The lock is released when the controller is closed.
+func OpenControllerLocked(info *hid.DeviceInfo, wait time.Duration) (*Controller, error) {
+	dev, err := OpenDeviceLocked(info, wait)
+	if err != nil {
+		return nil, err
+	}
+	return newController(dev), nil
+}
+
+// lockFileName returns the name of lock file for the serial number, with unsafe characters replaced.
+func lockFileName(sn string) string {
+	safe := strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, sn)
+	return safe + ".lock"
+}
+
+// readLockPID returns the PID recorded in the lock file, or 0 if it's unknown.
+func readLockPID(path string) int {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
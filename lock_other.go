@@ -0,0 +1,20 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package blink1
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// tryLockFile is not supported on this platform.
+func tryLockFile(f *os.File) (bool, error) {
+	return false, fmt.Errorf("%w: device lock on %s", ErrUnsupported, runtime.GOOS)
+}
+
+// unlockFile is not supported on this platform.
+func unlockFile(f *os.File) error {
+	return nil
+}
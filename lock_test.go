@@ -0,0 +1,94 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package blink1_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	b1 "github.com/b1ug/blink1-go"
+)
+
+func TestLockDevice(t *testing.T) {
+	dir, err := ioutil.TempDir("", "b1-lock")
+	if err != nil {
+		t.Fatalf("TempDir() got unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	old, had := os.LookupEnv("XDG_RUNTIME_DIR")
+	os.Setenv("XDG_RUNTIME_DIR", dir)
+	defer func() {
+		if had {
+			os.Setenv("XDG_RUNTIME_DIR", old)
+		} else {
+			os.Unsetenv("XDG_RUNTIME_DIR")
+		}
+	}()
+	if got, want := b1.LockDir(), filepath.Join(dir, "blink1"); got != want {
+		t.Errorf("LockDir() got %q, want %q", got, want)
+	}
+
+	l1, err := b1.LockDevice("EMU00001", 0)
+	if err != nil {
+		t.Fatalf("LockDevice() got unexpected error: %v", err)
+	}
+
+	// try-lock fails with the holder
+	_, err = b1.LockDevice("EMU00001", 0)
+	var le *b1.LockedError
+	if !errors.As(err, &le) || !errors.Is(err, b1.ErrLocked) {
+		t.Fatalf("LockDevice() on locked device got error: %v, want LockedError", err)
+	}
+	if le.PID != os.Getpid() || le.SerialNumber != "EMU00001" {
+		t.Errorf("LockedError got pid=%d sn=%s, want pid=%d sn=EMU00001", le.PID, le.SerialNumber, os.Getpid())
+	}
+
+	// other devices are independent
+	l2, err := b1.LockDevice("EMU00002", 0)
+	if err != nil {
+		t.Fatalf("LockDevice() on another device got unexpected error: %v", err)
+	}
+	_ = l2.Unlock()
+
+	// wait with timeout
+	start := time.Now()
+	if _, err = b1.LockDevice("EMU00001", 100*time.Millisecond); !errors.Is(err, b1.ErrLocked) {
+		t.Errorf("LockDevice() with wait on locked device got error: %v, want ErrLocked", err)
+	}
+	if el := time.Since(start); el < 100*time.Millisecond {
+		t.Errorf("LockDevice() with wait returned after %v, want at least 100ms", el)
+	}
+
+	// wait canceled by context
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if _, err = b1.LockDeviceContext(ctx, "EMU00001", 10*time.Second); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("LockDeviceContext() with expired context got error: %v, want context.DeadlineExceeded", err)
+	}
+	if el := time.Since(start); el > time.Second {
+		t.Errorf("LockDeviceContext() with expired context returned after %v, want soon", el)
+	}
+
+	// wait until released
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = l1.Unlock()
+	}()
+	l3, err := b1.LockDevice("EMU00001", 2*time.Second)
+	if err != nil {
+		t.Fatalf("LockDevice() waiting for release got unexpected error: %v", err)
+	}
+	if err := l3.Unlock(); err != nil {
+		t.Errorf("Unlock() got unexpected error: %v", err)
+	}
+	if err := l3.Unlock(); err != nil {
+		t.Errorf("Unlock() twice got unexpected error: %v", err)
+	}
+}
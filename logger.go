@@ -0,0 +1,195 @@
+package blink1
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// LogLevel represents the severity of a log message.
+type LogLevel int
+
+const (
+	// LevelTrace is for raw HID feature reports in hex
+	LevelTrace LogLevel = iota - 1
+	// LevelDebug is for decoded commands sent to the device
+	LevelDebug
+	// LevelInfo is for notable events
+	LevelInfo
+	// LevelWarn is for failed commands
+	LevelWarn
+	// LevelError is for unrecoverable errors
+	LevelError
+)
+
+// String returns a string representation of LogLevel.
+func (l LogLevel) String() string {
+	switch l {
+	case LevelTrace:
+		return "TRACE"
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("LEVEL(%d)", int(l))
+	}
+}
+
+// Logger is a minimal leveled and structured logger in the style of log/slog, it's compatible with Go 1.13.
+// The keyvals are alternating keys and values, e.g. "sn", "2000ABCD", "dur", 2*time.Millisecond.
+type Logger interface {
+	// Enabled reports whether the messages of the level are logged, it's used to skip formatting the costly ones.
+	Enabled(level LogLevel) bool
+	// Log logs the message of the level with the key-value pairs.
+	Log(level LogLevel, msg string, keyvals ...interface{})
+}
+
+// stdLogger is the Logger backed by the standard log package.
+type stdLogger struct {
+	l   *log.Logger
+	min LogLevel
+}
+
+// NewStdLogger returns a Logger which writes the messages of the minimum level and above to the standard logger, one line per message,
+// e.g. "DEBUG fadeToRGB #FF0000 500ms led=1 sn=2000ABCD dur=2ms". If the logger is nil, the default logger of the log package is used.
+func NewStdLogger(l *log.Logger, min LogLevel) Logger {
+	return &stdLogger{l: l, min: min}
+}
+
+func (s *stdLogger) Enabled(level LogLevel) bool {
+	return level >= s.min
+}
+
+func (s *stdLogger) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	if !s.Enabled(level) {
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString(level.String())
+	sb.WriteByte(' ')
+	sb.WriteString(msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 < len(keyvals) {
+			fmt.Fprintf(&sb, " %v=%v", keyvals[i], keyvals[i+1])
+		} else {
+			fmt.Fprintf(&sb, " %v=?", keyvals[i])
+		}
+	}
+	if s.l != nil {
+		_ = s.l.Output(2, sb.String())
+	} else {
+		_ = log.Output(2, sb.String())
+	}
+}
+
+// SetLogger sets the logger of the feature report exchanges with the device, or removes it if nil.
+// The decoded commands are logged at LevelDebug, or LevelWarn if failed, and the raw feature reports are logged at LevelTrace.
+// It's called synchronously with the device locked, so it should return quickly and must not call methods of the device.
+func (b1 *Device) SetLogger(l Logger) {
+	b1.mu.Lock()
+	defer b1.mu.Unlock()
+	b1.logger = l
+}
+
+// SetLogger works like Device.SetLogger on the device of the controller.
+func (c *Controller) SetLogger(l Logger) {
+	c.dev.SetLogger(l)
+}
+
+// SetLogger works like Device.SetLogger on all devices in the group.
+func (g *Group) SetLogger(l Logger) {
+	for _, c := range g.ctrls {
+		c.SetLogger(l)
+	}
+}
+
+// logExchange logs the raw and decoded commands of the exchange, and the response for reading.
+func (b1 *Device) logExchange(op string, cmds [][]byte, resp []byte, dur time.Duration, err error) {
+	lg := b1.logger
+	if lg.Enabled(LevelTrace) {
+		for _, cmd := range cmds {
+			lg.Log(LevelTrace, "hid send", "sn", b1.sn, "buf", hex.EncodeToString(cmd))
+		}
+		if resp != nil && err == nil {
+			lg.Log(LevelTrace, "hid recv", "sn", b1.sn, "buf", hex.EncodeToString(resp))
+		}
+	}
+	for i, cmd := range cmds {
+		if i < len(cmds)-1 {
+			// the leading commands only need the decoded line
+			if lg.Enabled(LevelDebug) {
+				lg.Log(LevelDebug, decodeCommand(cmd), "sn", b1.sn)
+			}
+			continue
+		}
+		if err != nil {
+			lg.Log(LevelWarn, decodeCommand(cmd), "sn", b1.sn, "op", op, "dur", dur, "err", err)
+		} else if lg.Enabled(LevelDebug) {
+			lg.Log(LevelDebug, decodeCommand(cmd), "sn", b1.sn, "dur", dur)
+		}
+	}
+}
+
+// decodeCommand returns the human-readable description of the feature report of a command, e.g. "fadeToRGB #FF0000 500ms led=1".
+func decodeCommand(buf []byte) string {
+	if len(buf) < 2 {
+		return fmt.Sprintf("invalid %x", buf)
+	}
+	arg := make([]byte, 8)
+	copy(arg, buf)
+	rgb := func() string {
+		return fmt.Sprintf("#%02X%02X%02X", arg[2], arg[3], arg[4])
+	}
+	fade := func() string {
+		return fmt.Sprintf("%dms", convFadeMsToDurMs(arg[5], arg[6]))
+	}
+	switch arg[1] {
+	case 'c':
+		return fmt.Sprintf("fadeToRGB %s %s led=%d", rgb(), fade(), arg[7])
+	case 'n':
+		return fmt.Sprintf("setRGBNow %s led=%d", rgb(), arg[7])
+	case 'r':
+		return fmt.Sprintf("readRGB led=%d", arg[7])
+	case 'p':
+		return fmt.Sprintf("playLoop on=%t start=%d end=%d count=%d", convByteToBool(arg[2]), arg[3], arg[4], arg[5])
+	case 'S':
+		return "readPlaystate"
+	case 'l':
+		return fmt.Sprintf("setLEDn led=%d", arg[2])
+	case 'P':
+		return fmt.Sprintf("setPatternLine %s %s pos=%d", rgb(), fade(), arg[7])
+	case 'R':
+		return fmt.Sprintf("readPatternLine pos=%d", arg[7])
+	case 'W':
+		return "savePattern"
+	case 'D':
+		return fmt.Sprintf("serverTickle on=%t timeout=%dms keep=%t start=%d end=%d", convByteToBool(arg[2]), convFadeMsToDurMs(arg[3], arg[4]), convByteToBool(arg[5]), arg[6], arg[7])
+	case 'B':
+		return fmt.Sprintf("setStartup on=%t start=%d end=%d count=%d", convByteToBool(arg[2]), arg[3], arg[4], arg[5])
+	case 'b':
+		return "readStartup"
+	case 'v':
+		return "getVersion"
+	case '!':
+		return "testCommand"
+	case 'e':
+		return fmt.Sprintf("readEEPROM addr=0x%02X", arg[2])
+	case 'E':
+		return fmt.Sprintf("writeEEPROM addr=0x%02X val=0x%02X", arg[2], arg[3])
+	case 'f':
+		return fmt.Sprintf("readNote id=%d off=%d", arg[2], arg[3])
+	case 'F':
+		return fmt.Sprintf("writeNote id=%d off=%d", arg[2], arg[3])
+	case 'U':
+		return "readChipID"
+	default:
+		return fmt.Sprintf("command %q", arg[1])
+	}
+}
@@ -0,0 +1,65 @@
+package blink1_test
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	b1 "github.com/b1ug/blink1-go"
+)
+
+func TestDevice_Logger(t *testing.T) {
+	emu := b1.NewEmulator(2, "EMU00001")
+	tr, err := emu.Open()
+	if err != nil {
+		t.Fatalf("Emulator.Open() got unexpected error: %v", err)
+	}
+	d, err := b1.NewDevice(tr)
+	if err != nil {
+		t.Fatalf("NewDevice() got unexpected error: %v", err)
+	}
+	defer d.Close()
+
+	var buf bytes.Buffer
+	d.SetLogger(b1.NewStdLogger(log.New(&buf, "", 0), b1.LevelTrace))
+
+	// decoded commands and raw buffers
+	_ = d.FadeToRGB(0xff, 0, 0, 500, b1.LED1)
+	_ = d.SetPatternLine(3, b1.DeviceLightState{G: 0x80, LED: b1.LED2, FadeTimeMsec: 100})
+	_, _ = d.GetVersion()
+	got := buf.String()
+	for _, line := range []string{
+		"TRACE hid send sn=EMU00001 buf=0163ff000000320100\n",
+		"DEBUG fadeToRGB #FF0000 500ms led=1 sn=EMU00001 dur=",
+		"DEBUG setLEDn led=2 sn=EMU00001\n",
+		"DEBUG setPatternLine #008000 100ms pos=3 sn=EMU00001 dur=",
+		"TRACE hid recv sn=EMU00001 buf=",
+		"DEBUG getVersion sn=EMU00001 dur=",
+	} {
+		if !strings.Contains(got, line) {
+			t.Errorf("log missing %q in:\n%s", line, got)
+		}
+	}
+
+	// failed commands are warnings, and trace is filtered by level
+	buf.Reset()
+	d.SetLogger(b1.NewStdLogger(log.New(&buf, "", 0), b1.LevelDebug))
+	emu.Unplug()
+	_ = d.SetRGBNow(0, 0, 0xff, b1.LEDAll)
+	got = buf.String()
+	if !strings.HasPrefix(got, "WARN setRGBNow #0000FF led=0 sn=EMU00001 op=write dur=") || !strings.Contains(got, "err=") {
+		t.Errorf("log of failed command got %q", got)
+	}
+	if strings.Contains(got, "TRACE") {
+		t.Errorf("log with debug level got trace lines: %q", got)
+	}
+
+	// removed
+	buf.Reset()
+	d.SetLogger(nil)
+	_ = d.SetRGBNow(0, 0, 0xff, b1.LEDAll)
+	if buf.Len() != 0 {
+		t.Errorf("log after SetLogger(nil) got %q", buf.String())
+	}
+}
@@ -0,0 +1,163 @@
+package blink1
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLatencyBuckets are the upper bounds in seconds of the latency histogram buckets used by NewMetrics.
+var DefaultLatencyBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// Metrics is an Observer which aggregates the command events into counters and latency histograms per device and command,
+// and it serves them in Prometheus text exposition format as an http.Handler.
+//
+// The exported metrics are:
+//
+//	blink1_commands_total{serial,cmd}              number of exchanges
+//	blink1_command_errors_total{serial,cmd}        number of failed exchanges
+//	blink1_command_retries_total{serial,cmd}       number of exchanges which are retries by RetryPolicy
+//	blink1_reconnects_total{serial}                number of reconnections in resilient mode
+//	blink1_command_duration_seconds{serial,cmd}    histogram of exchange latency
+type Metrics struct {
+	mu         sync.Mutex
+	buckets    []float64
+	cmds       map[metricsKey]*commandMetrics
+	reconnects map[string]uint64 // serial number -> count
+}
+
+// metricsKey is the label set of per-command metrics.
+type metricsKey struct {
+	sn  string
+	cmd byte
+}
+
+// commandMetrics is the aggregated metrics of a command on a device.
+type commandMetrics struct {
+	count   uint64
+	errors  uint64
+	retries uint64
+	sum     float64  // total duration in seconds
+	buckets []uint64 // non-cumulative counts of each bucket
+}
+
+// NewMetrics creates a metrics observer with DefaultLatencyBuckets.
+func NewMetrics() *Metrics {
+	return NewMetricsWithBuckets(DefaultLatencyBuckets)
+}
+
+// NewMetricsWithBuckets creates a metrics observer with the given upper bounds in seconds of the latency histogram buckets.
+func NewMetricsWithBuckets(buckets []float64) *Metrics {
+	bs := append([]float64(nil), buckets...)
+	sort.Float64s(bs)
+	return &Metrics{
+		buckets:    bs,
+		cmds:       make(map[metricsKey]*commandMetrics),
+		reconnects: make(map[string]uint64),
+	}
+}
+
+// ObserveCommand aggregates the command event.
+func (m *Metrics) ObserveCommand(ev CommandEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := metricsKey{sn: ev.SerialNumber, cmd: ev.Cmd}
+	cm, ok := m.cmds[key]
+	if !ok {
+		cm = &commandMetrics{buckets: make([]uint64, len(m.buckets))}
+		m.cmds[key] = cm
+	}
+	cm.count++
+	if ev.Err != nil {
+		cm.errors++
+	}
+	if ev.Retries > 0 {
+		cm.retries++
+	}
+	sec := ev.Duration.Seconds()
+	cm.sum += sec
+	if i := sort.SearchFloat64s(m.buckets, sec); i < len(m.buckets) {
+		cm.buckets[i]++
+	}
+	if ev.Reconnected {
+		m.reconnects[ev.SerialNumber]++
+	}
+}
+
+// ServeHTTP writes the metrics in Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.WriteText(w)
+}
+
+// WriteText writes the metrics in Prometheus text exposition format to the writer, in order of serial number and command.
+func (m *Metrics) WriteText(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]metricsKey, 0, len(m.cmds))
+	for k := range m.cmds {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].sn != keys[j].sn {
+			return keys[i].sn < keys[j].sn
+		}
+		return keys[i].cmd < keys[j].cmd
+	})
+
+	bw := bufio.NewWriter(w)
+	counter := func(name, help string, val func(cm *commandMetrics) uint64) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		for _, k := range keys {
+			fmt.Fprintf(bw, "%s{%s} %d\n", name, k.labels(), val(m.cmds[k]))
+		}
+	}
+	counter("blink1_commands_total", "Number of feature report exchanges with blink(1) devices.", func(cm *commandMetrics) uint64 { return cm.count })
+	counter("blink1_command_errors_total", "Number of failed feature report exchanges with blink(1) devices.", func(cm *commandMetrics) uint64 { return cm.errors })
+	counter("blink1_command_retries_total", "Number of feature report exchanges retried by the retry policy.", func(cm *commandMetrics) uint64 { return cm.retries })
+
+	// reconnections
+	const recName = "blink1_reconnects_total"
+	fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s counter\n", recName, "Number of reconnections of blink(1) devices in resilient mode.", recName)
+	sns := make([]string, 0, len(m.reconnects))
+	for sn := range m.reconnects {
+		sns = append(sns, sn)
+	}
+	sort.Strings(sns)
+	for _, sn := range sns {
+		fmt.Fprintf(bw, "%s{serial=\"%s\"} %d\n", recName, escapeLabel(sn), m.reconnects[sn])
+	}
+
+	// latency histogram
+	const durName = "blink1_command_duration_seconds"
+	fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s histogram\n", durName, "Latency of feature report exchanges with blink(1) devices.", durName)
+	for _, k := range keys {
+		cm, lbs := m.cmds[k], k.labels()
+		var cum uint64
+		for i, ub := range m.buckets {
+			cum += cm.buckets[i]
+			fmt.Fprintf(bw, "%s_bucket{%s,le=\"%s\"} %d\n", durName, lbs, strconv.FormatFloat(ub, 'g', -1, 64), cum)
+		}
+		fmt.Fprintf(bw, "%s_bucket{%s,le=\"+Inf\"} %d\n", durName, lbs, cm.count)
+		fmt.Fprintf(bw, "%s_sum{%s} %s\n", durName, lbs, strconv.FormatFloat(cm.sum, 'g', -1, 64))
+		fmt.Fprintf(bw, "%s_count{%s} %d\n", durName, lbs, cm.count)
+	}
+	return bw.Flush()
+}
+
+// labels returns the label pairs of the key in Prometheus text format.
+func (k metricsKey) labels() string {
+	return fmt.Sprintf("serial=\"%s\",cmd=\"%s\"", escapeLabel(k.sn), escapeLabel(string(rune(k.cmd))))
+}
+
+// escapeLabel escapes the label value for Prometheus text format.
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,103 @@
+package blink1
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CommandEvent describes a feature report exchange with a blink(1) device, it's reported to the Observer of the device after the exchange.
+type CommandEvent struct {
+	Op           string        // Operation of the command: "write" or "read"
+	Cmd          byte          // Command character, i.e. buf[1], e.g. 'c' for fading to RGB
+	SerialNumber string        // Serial number of the device
+	Gen          uint16        // Generation of the device: 1=mk1, 2=mk2, 3=mk3 etc.
+	Start        time.Time     // Time when the exchange started
+	Duration     time.Duration // Duration of the exchange, including the delay of reading and the reconnection if any
+	Retries      int           // Number of retries by the RetryPolicy of the controller before this exchange, 0 for the first attempt
+	Reconnected  bool          // Whether the device was reconnected during the exchange in resilient mode
+	Err          error         // Error of the exchange, nil if succeeded
+}
+
+func (ev CommandEvent) String() string {
+	return fmt.Sprintf("📡{%s %q sn=%s dur=%v retries=%d err=%v}", ev.Op, ev.Cmd, ev.SerialNumber, ev.Duration, ev.Retries, ev.Err)
+}
+
+// Observer is notified of every feature report exchange with the device, e.g. for latency and error metrics.
+// It's called synchronously with the device locked, so it should return quickly and must not call methods of the device.
+type Observer interface {
+	ObserveCommand(ev CommandEvent)
+}
+
+// ObserverFunc is an adapter to use a function as Observer.
+type ObserverFunc func(ev CommandEvent)
+
+// ObserveCommand calls f(ev).
+func (f ObserverFunc) ObserveCommand(ev CommandEvent) {
+	f(ev)
+}
+
+// retryKey is the context key of the number of retries so far by RetryPolicy.
+type retryKey struct{}
+
+// retriesFromContext returns the number of retries carried by the context, or 0 if there is none.
+func retriesFromContext(ctx context.Context) int {
+	if n, ok := ctx.Value(retryKey{}).(int); ok {
+		return n
+	}
+	return 0
+}
+
+// SetObserver sets the observer of the feature report exchanges with the device, or removes it if nil.
+func (b1 *Device) SetObserver(o Observer) {
+	b1.mu.Lock()
+	defer b1.mu.Unlock()
+	b1.observer = o
+}
+
+// SetObserver works like Device.SetObserver on the device of the controller.
+func (c *Controller) SetObserver(o Observer) {
+	c.dev.SetObserver(o)
+}
+
+// SetObserver works like Device.SetObserver on all devices in the group.
+func (g *Group) SetObserver(o Observer) {
+	for _, c := range g.ctrls {
+		c.SetObserver(o)
+	}
+}
+
+// exchange runs the I/O operation of the commands with do, reports it to the observer and logger, and returns the failure as CommandError of the last command.
+// The resp is the buffer of response for reading, or nil for writing.
+func (b1 *Device) exchange(ctx context.Context, op string, resp []byte, fn func(tr Transport) error, cmds ...[]byte) error {
+	var (
+		start = time.Now()
+		recon = b1.reconnects
+		cmd   = cmds[len(cmds)-1]
+		err   = b1.do(ctx, fn)
+		dur   = time.Since(start)
+	)
+	if err != nil {
+		err = newCommandError(op, cmd, b1.gen, err)
+	}
+	if b1.logger != nil {
+		b1.logExchange(op, cmds, resp, dur, err)
+	}
+	if b1.observer != nil {
+		ev := CommandEvent{
+			Op:           op,
+			SerialNumber: b1.sn,
+			Gen:          b1.gen,
+			Start:        start,
+			Duration:     dur,
+			Retries:      retriesFromContext(ctx),
+			Reconnected:  b1.reconnects != recon,
+			Err:          err,
+		}
+		if len(cmd) > 1 {
+			ev.Cmd = cmd[1]
+		}
+		b1.observer.ObserveCommand(ev)
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package blink1_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	b1 "github.com/b1ug/blink1-go"
+)
+
+func TestDevice_Observer(t *testing.T) {
+	emu := b1.NewEmulator(2, "EMU00001")
+	tr, err := emu.Open()
+	if err != nil {
+		t.Fatalf("Emulator.Open() got unexpected error: %v", err)
+	}
+	d, err := b1.NewDevice(tr)
+	if err != nil {
+		t.Fatalf("NewDevice() got unexpected error: %v", err)
+	}
+	defer d.Close()
+
+	var (
+		mu  sync.Mutex
+		evs []b1.CommandEvent
+	)
+	m := b1.NewMetrics()
+	d.SetObserver(b1.ObserverFunc(func(ev b1.CommandEvent) {
+		mu.Lock()
+		evs = append(evs, ev)
+		mu.Unlock()
+		m.ObserveCommand(ev)
+	}))
+
+	// write and read
+	if err := d.SetRGBNow(0xff, 0, 0, b1.LEDAll); err != nil {
+		t.Fatalf("SetRGBNow() got unexpected error: %v", err)
+	}
+	if _, err := d.GetVersion(); err != nil {
+		t.Fatalf("GetVersion() got unexpected error: %v", err)
+	}
+	if len(evs) != 2 {
+		t.Fatalf("got %d events, want 2", len(evs))
+	}
+	if ev := evs[0]; ev.Op != "write" || ev.Cmd != 'n' || ev.SerialNumber != "EMU00001" || ev.Gen != 2 || ev.Err != nil || ev.Retries != 0 {
+		t.Errorf("event of SetRGBNow() got %v", ev)
+	}
+	if ev := evs[1]; ev.Op != "read" || ev.Cmd != 'v' || ev.Err != nil || ev.Duration <= 0 {
+		t.Errorf("event of GetVersion() got %v", ev)
+	}
+
+	// failure and reconnection
+	emu.PowerCycle()
+	if _, err := d.GetVersion(); err == nil {
+		t.Fatalf("GetVersion() with stale handle should fail")
+	}
+	if ev := evs[2]; !errors.Is(ev.Err, b1.ErrDisconnected) || ev.Reconnected {
+		t.Errorf("event of failed GetVersion() got %v, want ErrDisconnected", ev)
+	}
+	emu.PowerCycle()
+	d.SetReconnectFunc(emu.Open)
+	if _, err := d.GetVersion(); err != nil {
+		t.Fatalf("GetVersion() should reconnect, got error: %v", err)
+	}
+	if ev := evs[3]; ev.Err != nil || !ev.Reconnected {
+		t.Errorf("event of reconnected GetVersion() got %v, want reconnected", ev)
+	}
+
+	// metrics in Prometheus text format
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type got %q", ct)
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE blink1_commands_total counter",
+		`blink1_commands_total{serial="EMU00001",cmd="n"} 1`,
+		`blink1_commands_total{serial="EMU00001",cmd="v"} 3`,
+		`blink1_command_errors_total{serial="EMU00001",cmd="v"} 1`,
+		`blink1_reconnects_total{serial="EMU00001"} 1`,
+		"# TYPE blink1_command_duration_seconds histogram",
+		`blink1_command_duration_seconds_bucket{serial="EMU00001",cmd="v",le="+Inf"} 3`,
+		`blink1_command_duration_seconds_count{serial="EMU00001",cmd="n"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics missing line %q in:\n%s", line, body)
+		}
+	}
+}
+
+func TestController_ObserverRetries(t *testing.T) {
+	tr := newFakeTransport(2)
+	c, err := b1.NewControllerWithTransport(tr)
+	if err != nil {
+		t.Fatalf("NewControllerWithTransport() got unexpected error: %v", err)
+	}
+	defer c.Close()
+	c.SetRetryPolicy(b1.RetryPolicy{Attempts: 3, Backoff: time.Millisecond})
+
+	var retries []int
+	c.SetObserver(b1.ObserverFunc(func(ev b1.CommandEvent) {
+		if ev.Cmd == 'P' {
+			retries = append(retries, ev.Retries)
+		}
+	}))
+	tr.failErr = errors.New("flaky hub")
+	if err := c.LoadPattern(0, 0, b1.StateSequence{b1.NewLightState(b1.ColorBlue, 0, b1.LEDAll)}); err == nil {
+		t.Fatalf("LoadPattern() on failing transport should fail")
+	}
+	if len(retries) != 3 || retries[0] != 0 || retries[1] != 1 || retries[2] != 2 {
+		t.Errorf("retries of events got %v, want [0 1 2]", retries)
+	}
+}
@@ -0,0 +1,300 @@
+package blink1
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"image/color"
+	"sync"
+)
+
+// Priority represents the priority of a command submitted to the command queue of Controller.
+// Commands with higher priority run first, and commands with the same priority run in the order of submission.
+// Commands of any higher priority also abort long uploads of lower priority ones between lines, see Controller.Submit for details.
+type Priority int
+
+const (
+	// PriorityLow is for background commands which can wait, e.g. uploading patterns ahead of time
+	PriorityLow Priority = iota - 1
+	// PriorityNormal is the default priority, which is also used by synchronous methods
+	PriorityNormal
+	// PriorityHigh is for commands which should be visible soon, e.g. status updates
+	PriorityHigh
+	// PriorityCritical is for urgent commands, e.g. alerts
+	PriorityCritical
+)
+
+// String returns a string representation of Priority.
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "Low"
+	case PriorityNormal:
+		return "Normal"
+	case PriorityHigh:
+		return "High"
+	case PriorityCritical:
+		return "Critical"
+	default:
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+}
+
+// Future represents the pending result of a command submitted to the command queue of Controller.
+type Future struct {
+	done   chan struct{}
+	err    error
+	cancel context.CancelFunc
+}
+
+// Done returns a channel that's closed when the command is finished, failed or canceled.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Err returns the error of the command after it's done, or nil if it's still pending or succeeded.
+func (f *Future) Err() error {
+	select {
+	case <-f.done:
+		return f.err
+	default:
+		return nil
+	}
+}
+
+// Wait blocks until the command is done and returns its error, or returns the error of the given context once it's done.
+func (f *Future) Wait(ctx context.Context) error {
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Cancel cancels the command: a pending command is dropped, and a running multi-line upload is aborted between lines.
+func (f *Future) Cancel() {
+	f.cancel()
+}
+
+// queueJob is a command waiting in the command queue.
+type queueJob struct {
+	pri Priority
+	seq uint64
+	ctx context.Context
+	fn  func(ctx context.Context) error
+	fut *Future
+}
+
+// run runs the command with the job carried by the context, and resolves the future.
+func (j *queueJob) run() {
+	err := j.ctx.Err()
+	if err == nil {
+		err = j.fn(context.WithValue(j.ctx, jobKey{}, j))
+	}
+	j.resolve(err)
+}
+
+// resolve resolves the future with the error and releases the context.
+func (j *queueJob) resolve(err error) {
+	j.fut.err = err
+	close(j.fut.done)
+	j.fut.cancel()
+}
+
+// jobHeap is a priority queue of commands, ordered by priority and then by submission order.
+type jobHeap []*queueJob
+
+func (h jobHeap) Len() int { return len(h) }
+func (h jobHeap) Less(i, j int) bool {
+	if h[i].pri != h[j].pri {
+		return h[i].pri > h[j].pri
+	}
+	return h[i].seq < h[j].seq
+}
+func (h jobHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *jobHeap) Push(x interface{}) { *h = append(*h, x.(*queueJob)) }
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	j := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return j
+}
+
+// cmdQueue is the command queue of Controller, which is served by a dispatcher goroutine started on demand.
+type cmdQueue struct {
+	mu      sync.Mutex
+	jobs    jobHeap
+	seq     uint64
+	wake    chan struct{} // signaled when a job is pushed
+	active  *queueJob     // job being run by the dispatcher, nil if idle
+	started bool
+	closed  bool
+}
+
+// jobKey is the context key of the running job of the command queue.
+type jobKey struct{}
+
+// jobFromContext returns the running job of the command queue, or nil for synchronous calls.
+func jobFromContext(ctx context.Context) *queueJob {
+	j, _ := ctx.Value(jobKey{}).(*queueJob)
+	return j
+}
+
+// Submit puts the command into the command queue of the controller with the given priority, and returns the future of its result.
+// The command runs in the dispatcher goroutine with a context derived from the given one, and it should use the Context variants of the controller methods with that context.
+// Commands with higher priority also preempt long uploads of pattern lines from lower priority commands or synchronous calls,
+// which are aborted between lines with an error wrapping ErrPreempted, so they never override the preempting commands. Submit them again to retry.
+// Pending commands fail with ErrClosed when the controller is closed.
+func (c *Controller) Submit(ctx context.Context, pri Priority, cmd func(ctx context.Context) error) *Future {
+	ctx, cancel := context.WithCancel(ctx)
+	job := &queueJob{
+		pri: pri,
+		ctx: ctx,
+		fn:  cmd,
+		fut: &Future{done: make(chan struct{}), cancel: cancel},
+	}
+
+	q := &c.queue
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		job.resolve(ErrClosed)
+		return job.fut
+	}
+	if !q.started {
+		q.started = true
+		q.wake = make(chan struct{}, 1)
+		c.workers.Add(1)
+		go c.dispatch()
+	}
+	q.seq++
+	job.seq = q.seq
+	heap.Push(&q.jobs, job)
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job.fut
+}
+
+// dispatch runs the commands in the queue one by one until the controller is closed.
+func (c *Controller) dispatch() {
+	defer c.workers.Done()
+	q := &c.queue
+	for {
+		select {
+		case <-q.wake:
+		case <-c.closeCh:
+		}
+		for job := q.pop(); job != nil; job = q.pop() {
+			if q.isClosed() {
+				// fail the pending jobs
+				job.resolve(ErrClosed)
+			} else {
+				q.setActive(job)
+				job.run()
+				q.setActive(nil)
+			}
+		}
+		if pending, closed := q.drainClosed(); closed {
+			// fail the jobs pushed after the loop above drained the queue
+			for _, job := range pending {
+				job.resolve(ErrClosed)
+			}
+			return
+		}
+	}
+}
+
+// pop removes and returns the first job, or nil if there is none.
+func (q *cmdQueue) pop() *queueJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.jobs) == 0 {
+		return nil
+	}
+	return heap.Pop(&q.jobs).(*queueJob)
+}
+
+// close marks the queue as closed, so no more jobs can be submitted and the pending ones will fail.
+func (q *cmdQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+}
+
+// drainClosed removes and returns all remaining jobs if the queue is closed.
+// No more jobs can be pushed once it's closed, so nothing is left behind after the dispatcher quits.
+func (q *cmdQueue) drainClosed() ([]*queueJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		return nil, false
+	}
+	jobs := q.jobs
+	q.jobs = nil
+	return jobs, true
+}
+
+// isClosed returns true if the queue is closed.
+func (q *cmdQueue) isClosed() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.closed
+}
+
+// setActive sets the job being run by the dispatcher.
+func (q *cmdQueue) setActive(job *queueJob) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.active = job
+}
+
+// preempted returns true if a command with higher priority than the running one is waiting, either queued or taken by the dispatcher.
+// The running command is the job carried by the context, or a synchronous call of PriorityNormal.
+func (q *cmdQueue) preempted(ctx context.Context) bool {
+	self, pri := jobFromContext(ctx), PriorityNormal
+	if self != nil {
+		pri = self.pri
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.jobs) > 0 && q.jobs[0].pri > pri {
+		return true
+	}
+	return q.active != nil && q.active != self && q.active.pri > pri
+}
+
+// PlayColorAsync works like PlayColor but runs in the command queue with the given priority.
+func (c *Controller) PlayColorAsync(pri Priority, cl color.Color) *Future {
+	return c.Submit(context.Background(), pri, func(ctx context.Context) error {
+		return c.PlayColorContext(ctx, cl)
+	})
+}
+
+// PlayStateAsync works like PlayState but runs in the command queue with the given priority.
+func (c *Controller) PlayStateAsync(pri Priority, st LightState) *Future {
+	return c.Submit(context.Background(), pri, func(ctx context.Context) error {
+		return c.PlayStateContext(ctx, st)
+	})
+}
+
+// PlayPatternAsync works like PlayPattern but runs in the command queue with the given priority.
+func (c *Controller) PlayPatternAsync(pri Priority, pt Pattern) *Future {
+	return c.Submit(context.Background(), pri, func(ctx context.Context) error {
+		return c.PlayPatternContext(ctx, pt)
+	})
+}
+
+// LoadPatternAsync works like LoadPattern but runs in the command queue with the given priority.
+func (c *Controller) LoadPatternAsync(pri Priority, posStart, posEnd uint, seq StateSequence) *Future {
+	return c.Submit(context.Background(), pri, func(ctx context.Context) error {
+		return c.LoadPatternContext(ctx, posStart, posEnd, seq)
+	})
+}
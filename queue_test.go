@@ -0,0 +1,122 @@
+package blink1_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	b1 "github.com/b1ug/blink1-go"
+)
+
+func TestController_QueueOrder(t *testing.T) {
+	c := newEmulatedController(t, 2)
+	defer c.Close()
+
+	// block the dispatcher, then queue commands with different priorities
+	release := make(chan struct{})
+	c.Submit(context.Background(), b1.PriorityNormal, func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	var order []b1.Priority
+	var futs []*b1.Future
+	for _, pri := range []b1.Priority{b1.PriorityLow, b1.PriorityNormal, b1.PriorityCritical, b1.PriorityHigh} {
+		pri := pri
+		futs = append(futs, c.Submit(context.Background(), pri, func(ctx context.Context) error {
+			order = append(order, pri)
+			return nil
+		}))
+	}
+	close(release)
+	for _, f := range futs {
+		if err := f.Wait(context.Background()); err != nil {
+			t.Fatalf("Future.Wait() got unexpected error: %v", err)
+		}
+	}
+	want := []b1.Priority{b1.PriorityCritical, b1.PriorityHigh, b1.PriorityNormal, b1.PriorityLow}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("commands ran in order %v, want %v", order, want)
+		}
+	}
+}
+
+func TestController_QueuePreempt(t *testing.T) {
+	c := newEmulatedController(t, 2)
+
+	// start a long upload and preempt it with an alert
+	seq := make(b1.StateSequence, 32)
+	for i := range seq {
+		seq[i] = b1.NewLightState(b1.ColorBlue, 0, b1.LEDAll)
+	}
+	upload := c.PlayPatternAsync(b1.PriorityLow, b1.Pattern{StartPosition: 0, EndPosition: 31, RepeatTimes: 0, Sequence: seq})
+	time.Sleep(100 * time.Millisecond)
+	alert := c.PlayColorAsync(b1.PriorityCritical, b1.ColorRed)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := alert.Wait(ctx); err != nil {
+		t.Fatalf("alert Future.Wait() got unexpected error: %v", err)
+	}
+
+	// the upload is aborted and never plays over the alert
+	if err := upload.Wait(ctx); !errors.Is(err, b1.ErrPreempted) {
+		t.Errorf("upload Future.Wait() got error: %v, want ErrPreempted", err)
+	}
+	if playing, _ := c.IsPatternPlaying(); playing {
+		t.Errorf("IsPatternPlaying() after preempted upload = true, want false")
+	}
+	if cl, _ := c.ReadColor(b1.LED1); b1.ColorToHex(cl) != "#FF0000" {
+		t.Errorf("ReadColor() after alert = %v, want #FF0000", cl)
+	}
+
+	// synchronous uploads are preempted too
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.LoadPattern(0, 31, seq)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if err := c.PlayColorAsync(b1.PriorityHigh, b1.ColorGreen).Wait(ctx); err != nil {
+		t.Fatalf("alert Future.Wait() got unexpected error: %v", err)
+	}
+	if err := <-errCh; !errors.Is(err, b1.ErrPreempted) {
+		t.Errorf("LoadPattern() got error: %v, want ErrPreempted", err)
+	}
+
+	// cancel a running upload
+	upload = c.LoadPatternAsync(b1.PriorityLow, 0, 31, seq)
+	time.Sleep(50 * time.Millisecond)
+	upload.Cancel()
+	if err := upload.Wait(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Errorf("upload Future.Wait() got error: %v, want context.Canceled", err)
+	}
+
+	// closed queue
+	c.Close()
+	if err := c.PlayColorAsync(b1.PriorityHigh, b1.ColorRed).Wait(context.Background()); !errors.Is(err, b1.ErrClosed) {
+		t.Errorf("PlayColorAsync() after Close got error: %v, want ErrClosed", err)
+	}
+}
+
+func TestController_QueueClose(t *testing.T) {
+	// submit commands concurrently with closing, all futures must be resolved
+	for i := 0; i < 50; i++ {
+		c := newEmulatedController(t, 2)
+		futs := make(chan *b1.Future, 100)
+		go func() {
+			defer close(futs)
+			for j := 0; j < 100; j++ {
+				futs <- c.Submit(context.Background(), b1.PriorityNormal, func(ctx context.Context) error { return nil })
+			}
+		}()
+		c.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		for f := range futs {
+			if err := f.Wait(ctx); err != nil && !errors.Is(err, b1.ErrClosed) {
+				t.Fatalf("Future.Wait() racing with Close got error: %v, want nil or ErrClosed", err)
+			}
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,154 @@
+package blink1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// deviceReplay keeps the last known state of the device for replaying after reconnecting, it's stored as raw feature reports.
+type deviceReplay struct {
+	colors  [LED2 + 1][]byte      // LED index -> last color command, i.e. 'c' or 'n'
+	pattern [maxPattern2][][]byte // position -> last pattern line commands, i.e. 'l' and 'P'
+	tickle  []byte                // last active server tickle command, i.e. 'D'
+}
+
+// SetAutoReconnect enables or disables the resilient mode of the device, which is disabled by default.
+//
+// In resilient mode, if a HID operation fails as the device is disconnected, the device will be reopened by finding the connected blink(1) device with the same serial number,
+// the last known state, i.e. the current color, the loaded pattern and the active tickle, will be replayed, and then the operation will be retried once.
+// It's useful for long-running programs to survive unplugging and re-plugging the device or USB hub resets.
+// Devices opened by Hidraw are reopened by rescanning the hidraw devices, so they keep using the same backend.
+func (b1 *Device) SetAutoReconnect(on bool) {
+	if on {
+		b1.SetReconnectFunc(b1.reopenBySerialNumber)
+	} else {
+		b1.SetReconnectFunc(nil)
+	}
+}
+
+// SetReconnectFunc enables the resilient mode of the device with the given function to reopen the transport, or disables it if the function is nil.
+// It works like SetAutoReconnect(), but it's useful for devices created by NewDevice() with transports other than the default HID one.
+func (b1 *Device) SetReconnectFunc(reopen func() (Transport, error)) {
+	b1.mu.Lock()
+	defer b1.mu.Unlock()
+
+	b1.reopen = reopen
+	if reopen == nil {
+		b1.replay = nil
+	} else if b1.replay == nil {
+		b1.replay = &deviceReplay{}
+	}
+}
+
+// transportReopener is implemented by the transports which can find and reopen the same device with their own backend after it's re-plugged, e.g. the hidraw one.
+type transportReopener interface {
+	reopen() (Transport, error)
+}
+
+// reopenBySerialNumber reopens the transport of the connected blink(1) device with the same serial number.
+// The current transport reopens itself if it supports, so the device keeps its backend, otherwise the default HID transport is opened.
+func (b1 *Device) reopenBySerialNumber() (Transport, error) {
+	if r, ok := b1.dev.(transportReopener); ok {
+		return r.reopen()
+	}
+	info, err := FindDeviceInfoBySerialNumber(b1.sn)
+	if err != nil {
+		return nil, err
+	}
+	return openHIDTransport(info)
+}
+
+// do runs the I/O operation on the transport if the device is not closed and the context is not done yet.
+// In resilient mode, if it fails with ErrDisconnected, the device will be reconnected and the operation will be retried once.
+// Other errors are returned as is.
+func (b1 *Device) do(ctx context.Context, op func(tr Transport) error) error {
+	if b1.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := op(b1.dev)
+	if err == nil || b1.reopen == nil || ctx.Err() != nil || !errors.Is(err, ErrDisconnected) {
+		return err
+	}
+	if re := b1.reconnect(ctx); re != nil {
+		return fmt.Errorf("%w (reconnect fail: %v)", err, re)
+	}
+	return op(b1.dev)
+}
+
+// reconnect reopens the transport and replays the last known state to the device.
+func (b1 *Device) reconnect(ctx context.Context) error {
+	// reopen the transport and drop the stale one
+	tr, err := b1.reopen()
+	if err != nil {
+		return err
+	}
+	b1.dev.Close()
+	b1.dev = tr
+	b1.reconnects++
+	if info := tr.GetDeviceInfo(); info != nil {
+		b1.info = info
+	}
+
+	// replay the pattern lines, colors and tickle in order
+	var bufs [][]byte
+	for _, line := range b1.replay.pattern {
+		bufs = append(bufs, line...)
+	}
+	for _, buf := range b1.replay.colors {
+		if buf != nil {
+			bufs = append(bufs, buf)
+		}
+	}
+	if b1.replay.tickle != nil {
+		bufs = append(bufs, b1.replay.tickle)
+	}
+	for i, buf := range bufs {
+		if i > 0 {
+			// sleep for a little while to avoid hardware errors
+			if err := sleepContext(ctx, opsInterval); err != nil {
+				return err
+			}
+		}
+		if err := tr.WriteFeature(buf); err != nil {
+			return fmt.Errorf("replay %q: %w", buf[1], err)
+		}
+	}
+	return nil
+}
+
+// track keeps the feature reports successfully sent to the device as the last known state for replaying in resilient mode.
+func (b1 *Device) track(bufs ...[]byte) {
+	rp := b1.replay
+	if rp == nil {
+		return
+	}
+
+	last := bufs[len(bufs)-1]
+	switch last[1] {
+	case 'c', 'n':
+		// color for all LEDs overrides the others
+		if ledN := last[7]; ledN == 0 {
+			rp.colors = [LED2 + 1][]byte{last}
+		} else if ledN <= byte(LED2) {
+			rp.colors[ledN] = last
+		}
+	case 'P':
+		if pos := last[7]; uint(pos) < maxPattern2 {
+			rp.pattern[pos] = bufs
+		}
+	case 'D':
+		if convByteToBool(last[2]) {
+			rp.tickle = last
+		} else {
+			rp.tickle = nil
+		}
+		if !convByteToBool(last[5]) {
+			// all LEDs are turned off by the device
+			rp.colors = [LED2 + 1][]byte{}
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package blink1
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	hid "github.com/b1ug/gid"
+)
+
+// operations in transcript
+const (
+	opInfo  = "info"
+	opWrite = "write"
+	opRead  = "read"
+)
+
+var (
+	errReplayEnded    = errors.New("b1: replay transcript ended")
+	errReplayNoInfo   = errors.New("b1: replay transcript has no device info")
+	errReplayMismatch = errors.New("b1: replay transcript mismatch")
+)
+
+// transcriptErrKinds maps the error kinds in transcript to the sentinel errors, so they can be matched by errors.Is after replaying.
+var transcriptErrKinds = []struct {
+	kind string
+	err  error
+}{
+	{"disconnected", ErrDisconnected},
+	{"closed", ErrClosed},
+	{"locked", ErrLocked},
+	{"not_found", ErrDeviceNotFound},
+}
+
+// TranscriptEntry is a line in the transcript of HID traffic, which is encoded as JSON Lines.
+//
+// The first entry of a transcript is always the device info, and the following entries are the feature reports in the order of exchange.
+// Data of feature reports is in hex format, e.g. "0163ff000000320100" for fading LED 1 to red over 500ms.
+type TranscriptEntry struct {
+	Time time.Time       `json:"time"`           // When the exchange was completed
+	Op   string          `json:"op"`             // Operation: "info", "write" or "read"
+	Data string          `json:"data,omitempty"` // Feature report data in hex
+	Err  string          `json:"err,omitempty"`  // Error message if the exchange failed
+	Kind string          `json:"kind,omitempty"` // Kind of the error if it's a known one, e.g. "disconnected" for ErrDisconnected
+	Info *hid.DeviceInfo `json:"info,omitempty"` // Device info for "info" operation
+}
+
+// error rebuilds the recorded error of the entry, which wraps the sentinel error of its kind, or returns nil if the exchange succeeded.
+func (ent TranscriptEntry) error() error {
+	if ent.Err == "" {
+		return nil
+	}
+	for _, k := range transcriptErrKinds {
+		if k.kind == ent.Kind {
+			return fmt.Errorf("%s: %w", ent.Err, k.err)
+		}
+	}
+	return errors.New(ent.Err)
+}
+
+// Recorder is a Transport wrapper that records every feature report sent to and received from the underlying transport with timestamps.
+// The transcript can be replayed later by Replayer to reproduce the session deterministically without the device.
+type Recorder struct {
+	mu  sync.Mutex
+	tr  Transport
+	c   io.Closer // for files opened by the recorder
+	enc *json.Encoder
+	err error // the first error of writing transcript
+}
+
+// NewRecorder creates a recorder which wraps the given transport and writes the transcript to the given writer.
+func NewRecorder(tr Transport, w io.Writer) *Recorder {
+	r := &Recorder{
+		tr:  tr,
+		enc: json.NewEncoder(w),
+	}
+	r.log(TranscriptEntry{Op: opInfo, Info: tr.GetDeviceInfo()}, nil)
+	return r
+}
+
+// RecordToFile creates a recorder which wraps the given transport and writes the transcript to the file of given path.
+// The file will be created or truncated, and it will be closed along with the recorder.
+func RecordToFile(tr Transport, path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := NewRecorder(tr, f)
+	r.c = f
+	return r, nil
+}
+
+// Err returns the first error occurred while writing the transcript, if any.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// WriteFeature sends the feature report to the underlying transport and records it.
+func (r *Recorder) WriteFeature(buf []byte) error {
+	err := r.tr.WriteFeature(buf)
+	r.log(TranscriptEntry{Op: opWrite, Data: hex.EncodeToString(buf)}, err)
+	return err
+}
+
+// ReadFeature gets the feature report from the underlying transport and records it.
+func (r *Recorder) ReadFeature(buf []byte) (int, error) {
+	n, err := r.tr.ReadFeature(buf)
+	var data string
+	if n > 0 && n <= len(buf) {
+		data = hex.EncodeToString(buf[:n])
+	}
+	r.log(TranscriptEntry{Op: opRead, Data: data}, err)
+	return n, err
+}
+
+// Close closes the underlying transport and the transcript file opened by the recorder.
+func (r *Recorder) Close() {
+	r.tr.Close()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.c != nil {
+		if err := r.c.Close(); err != nil && r.err == nil {
+			r.err = err
+		}
+		r.c = nil
+	}
+}
+
+// GetDeviceInfo returns the HID device info of the underlying transport.
+func (r *Recorder) GetDeviceInfo() *hid.DeviceInfo {
+	return r.tr.GetDeviceInfo()
+}
+
+// log writes the entry to the transcript.
+func (r *Recorder) log(ent TranscriptEntry, err error) {
+	ent.Time = time.Now()
+	if err != nil {
+		ent.Err = err.Error()
+		for _, k := range transcriptErrKinds {
+			if errors.Is(err, k.err) {
+				ent.Kind = k.kind
+				break
+			}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e := r.enc.Encode(ent); e != nil && r.err == nil {
+		r.err = e
+	}
+}
+
+// Replayer is a Transport which serves the feature reports from a transcript recorded by Recorder.
+// The written feature reports are verified against the transcript in order, and the read ones are served from it, including the recorded errors.
+type Replayer struct {
+	mu      sync.Mutex
+	info    *hid.DeviceInfo
+	entries []TranscriptEntry
+	pos     int
+}
+
+// NewReplayer creates a replayer with the transcript read from the given reader.
+func NewReplayer(rd io.Reader) (*Replayer, error) {
+	r := &Replayer{}
+	sc := bufio.NewScanner(rd)
+	sc.Buffer(make([]byte, 0, 4096), 1<<20)
+	for ln := 1; sc.Scan(); ln++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var ent TranscriptEntry
+		if err := json.Unmarshal(sc.Bytes(), &ent); err != nil {
+			return nil, fmt.Errorf("b1: invalid transcript at line %d: %w", ln, err)
+		}
+		if ent.Op == opInfo {
+			if r.info == nil {
+				r.info = ent.Info
+			}
+			continue
+		}
+		r.entries = append(r.entries, ent)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if r.info == nil {
+		return nil, errReplayNoInfo
+	}
+	return r, nil
+}
+
+// OpenReplayFile creates a replayer with the transcript read from the file of given path.
+func OpenReplayFile(path string) (*Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewReplayer(f)
+}
+
+// Remaining returns the number of feature reports left in the transcript.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries) - r.pos
+}
+
+// WriteFeature verifies the feature report against the next one in the transcript.
+func (r *Replayer) WriteFeature(buf []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ent, err := r.next(opWrite)
+	if err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(buf); got != ent.Data {
+		return fmt.Errorf("%w: #%d write %s, want %s", errReplayMismatch, r.pos, got, ent.Data)
+	}
+	return ent.error()
+}
+
+// ReadFeature serves the next feature report in the transcript.
+func (r *Replayer) ReadFeature(buf []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ent, err := r.next(opRead)
+	if err != nil {
+		return 0, err
+	}
+	if err := ent.error(); err != nil {
+		return 0, err
+	}
+	data, err := hex.DecodeString(ent.Data)
+	if err != nil {
+		return 0, fmt.Errorf("b1: invalid transcript data #%d: %w", r.pos, err)
+	}
+	return copy(buf, data), nil
+}
+
+// Close does nothing for replayer.
+func (r *Replayer) Close() {}
+
+// GetDeviceInfo returns the HID device info recorded in the transcript.
+func (r *Replayer) GetDeviceInfo() *hid.DeviceInfo {
+	return r.info
+}
+
+// next returns the next entry in the transcript and verifies the operation.
+func (r *Replayer) next(op string) (TranscriptEntry, error) {
+	if r.pos >= len(r.entries) {
+		return TranscriptEntry{}, errReplayEnded
+	}
+	ent := r.entries[r.pos]
+	r.pos++
+	if ent.Op != op {
+		return ent, fmt.Errorf("%w: #%d got %s, want %s", errReplayMismatch, r.pos, op, ent.Op)
+	}
+	return ent, nil
+}
@@ -0,0 +1,145 @@
+package blink1_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	b1 "github.com/b1ug/blink1-go"
+)
+
+func TestRecorder_Replay(t *testing.T) {
+	// record a session with emulator
+	var buf bytes.Buffer
+	rec := b1.NewRecorder(b1.NewEmulator(2, "REC00001"), &buf)
+	c, err := b1.NewControllerWithTransport(rec)
+	if err != nil {
+		t.Fatalf("NewControllerWithTransport() got unexpected error: %v", err)
+	}
+	seq := b1.StateSequence{
+		b1.NewLightState(b1.ColorRed, 100*time.Millisecond, b1.LED1),
+		b1.NewLightState(b1.ColorBlue, 100*time.Millisecond, b1.LED2),
+	}
+	if err := c.LoadPattern(0, 1, seq); err != nil {
+		t.Fatalf("LoadPattern() got unexpected error: %v", err)
+	}
+	want, err := c.ReadPattern()
+	if err != nil {
+		t.Fatalf("ReadPattern() got unexpected error: %v", err)
+	}
+	c.Close()
+	if err := rec.Err(); err != nil {
+		t.Fatalf("Recorder.Err() got unexpected error: %v", err)
+	}
+	if lines, want := strings.Count(buf.String(), "\n"), 1+2+2*2+32*2; lines != want {
+		t.Errorf("Recorder got %d lines of transcript, want %d", lines, want)
+	}
+
+	// replay the session
+	rep, err := b1.NewReplayer(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReplayer() got unexpected error: %v", err)
+	}
+	c, err = b1.NewControllerWithTransport(rep)
+	if err != nil {
+		t.Fatalf("NewControllerWithTransport() got unexpected error: %v", err)
+	}
+	defer c.Close()
+	if sn := c.GetDevice().GetSerialNumber(); sn != "REC00001" {
+		t.Errorf("replayed device serial number = %q, want %q", sn, "REC00001")
+	}
+	if err := c.LoadPattern(0, 1, seq); err != nil {
+		t.Fatalf("LoadPattern() on replay got unexpected error: %v", err)
+	}
+	got, err := c.ReadPattern()
+	if err != nil {
+		t.Fatalf("ReadPattern() on replay got unexpected error: %v", err)
+	}
+	if got.String() != want.String() || got[1].String() != want[1].String() {
+		t.Errorf("ReadPattern() on replay = %v, want %v", got, want)
+	}
+	if n := rep.Remaining(); n != 0 {
+		t.Errorf("Replayer.Remaining() = %d, want 0", n)
+	}
+	if _, err := c.GetDevice().GetVersion(); err == nil {
+		t.Errorf("GetVersion() should fail after the transcript ended")
+	}
+}
+
+func TestReplayer_Mismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blink1-record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "session.jsonl")
+
+	// record to file
+	rec, err := b1.RecordToFile(b1.NewEmulator(2, "REC00002"), path)
+	if err != nil {
+		t.Fatalf("RecordToFile() got unexpected error: %v", err)
+	}
+	d, _ := b1.NewDevice(rec)
+	if err := d.FadeToRGB(0xff, 0, 0, 500, b1.LED1); err != nil {
+		t.Fatalf("FadeToRGB() got unexpected error: %v", err)
+	}
+	d.Close()
+
+	// replay with a different command
+	rep, err := b1.OpenReplayFile(path)
+	if err != nil {
+		t.Fatalf("OpenReplayFile() got unexpected error: %v", err)
+	}
+	d, _ = b1.NewDevice(rep)
+	if err := d.FadeToRGB(0, 0xff, 0, 500, b1.LED1); err == nil {
+		t.Errorf("FadeToRGB() with different color should fail on replay")
+	}
+
+	// invalid transcripts
+	if _, err := b1.NewReplayer(strings.NewReader(`{"op":"write","data":"01"}`)); err == nil {
+		t.Errorf("NewReplayer() without device info should fail")
+	}
+	if _, err := b1.NewReplayer(strings.NewReader(`not json`)); err == nil {
+		t.Errorf("NewReplayer() with invalid json should fail")
+	}
+}
+
+func TestRecorder_ReplayDisconnect(t *testing.T) {
+	// record a session with an unplug
+	var buf bytes.Buffer
+	emu := b1.NewEmulator(2, "REC00003")
+	d, err := b1.NewDevice(b1.NewRecorder(emu, &buf))
+	if err != nil {
+		t.Fatalf("NewDevice() got unexpected error: %v", err)
+	}
+	if err := d.FadeToRGB(0xff, 0, 0, 0, b1.LEDAll); err != nil {
+		t.Fatalf("FadeToRGB() got unexpected error: %v", err)
+	}
+	emu.Unplug()
+	if err := d.FadeToRGB(0, 0xff, 0, 0, b1.LEDAll); !errors.Is(err, b1.ErrDisconnected) {
+		t.Fatalf("FadeToRGB() on unplugged device got error: %v, want ErrDisconnected", err)
+	}
+	d.Close()
+
+	// the sentinel error survives the round trip
+	rep, err := b1.NewReplayer(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReplayer() got unexpected error: %v", err)
+	}
+	d, err = b1.NewDevice(rep)
+	if err != nil {
+		t.Fatalf("NewDevice() got unexpected error: %v", err)
+	}
+	defer d.Close()
+	if err := d.FadeToRGB(0xff, 0, 0, 0, b1.LEDAll); err != nil {
+		t.Fatalf("FadeToRGB() on replay got unexpected error: %v", err)
+	}
+	if err := d.FadeToRGB(0, 0xff, 0, 0, b1.LEDAll); !errors.Is(err, b1.ErrDisconnected) {
+		t.Errorf("FadeToRGB() on replayed unplug got error: %v, want ErrDisconnected", err)
+	}
+}
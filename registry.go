@@ -0,0 +1,366 @@
+package blink1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	hid "github.com/b1ug/gid"
+)
+
+// RegistryEntry is the identity of a blink(1) device in the registry, keyed by serial number.
+type RegistryEntry struct {
+	SerialNumber string   `json:"serial"`          // Serial number of the device
+	Alias        string   `json:"alias,omitempty"` // Unique human-readable name of the device, e.g. "desk-alice"
+	Tags         []string `json:"tags,omitempty"`  // Tags of the device for grouping, e.g. "build-status"
+}
+
+func (en RegistryEntry) String() string {
+	return fmt.Sprintf("🏷️{sn=%s alias=%q tags=%v}", en.SerialNumber, en.Alias, en.Tags)
+}
+
+// registryFile is the JSON layout of the registry file.
+type registryFile struct {
+	Devices []RegistryEntry `json:"devices"`
+}
+
+// Registry maps the serial numbers of blink(1) devices to aliases and tags, and it can be persisted as a local JSON file.
+type Registry struct {
+	mu      sync.RWMutex
+	path    string
+	entries map[string]*RegistryEntry // serial number -> entry
+	source  func() []*hid.DeviceInfo  // nil for the devices connected to the system
+}
+
+// NewRegistry creates an empty registry in memory.
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string]*RegistryEntry)}
+}
+
+// DefaultRegistryPath returns the default path of the registry file, i.e. blink1/registry.json under the user's configuration directory.
+func DefaultRegistryPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "blink1", "registry.json"), nil
+}
+
+// LoadRegistry loads the registry from the JSON file at the given path, and it will be saved to the same path by Save().
+// An empty registry is returned if the file doesn't exist.
+func LoadRegistry(path string) (*Registry, error) {
+	r := NewRegistry()
+	r.path = path
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var rf registryFile
+	if err := json.Unmarshal(data, &rf); err != nil {
+		return nil, fmt.Errorf("b1: invalid registry file %s: %w", path, err)
+	}
+	for _, en := range rf.Devices {
+		if en.SerialNumber == "" {
+			continue
+		}
+		if err := r.SetAlias(en.SerialNumber, en.Alias); err != nil {
+			return nil, fmt.Errorf("b1: invalid registry file %s: %w", path, err)
+		}
+		r.AddTags(en.SerialNumber, en.Tags...)
+	}
+	return r, nil
+}
+
+// LoadDefaultRegistry loads the registry from the default path.
+func LoadDefaultRegistry() (*Registry, error) {
+	path, err := DefaultRegistryPath()
+	if err != nil {
+		return nil, err
+	}
+	return LoadRegistry(path)
+}
+
+// Save saves the registry to the path it's loaded from.
+func (r *Registry) Save() error {
+	r.mu.RLock()
+	path := r.path
+	r.mu.RUnlock()
+	if path == "" {
+		return errors.New("b1: registry has no file path")
+	}
+	return r.SaveTo(path)
+}
+
+// SaveTo saves the registry to the JSON file at the given path, the parent directories are created if needed.
+// The file is written to a temporary file in the same directory and renamed over the target, so it's never left truncated.
+func (r *Registry) SaveTo(path string) error {
+	data, err := json.MarshalIndent(registryFile{Devices: r.Entries()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// writeFileAtomic writes the data to a temporary file in the directory of the path, and renames it over the path.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// SetSource sets the function to enumerate blink(1) devices for lookups, it's the devices connected to the system by default.
+func (r *Registry) SetSource(source func() []*hid.DeviceInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.source = source
+}
+
+// SetAlias sets the alias of the device with the given serial number, or removes it if the alias is empty.
+// An error will be returned if the alias is taken by another device.
+func (r *Registry) SetAlias(sn, alias string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if alias != "" {
+		if other, ok := r.serialByAlias(alias); ok && other != sn {
+			return fmt.Errorf("%w: %q by %s", ErrAliasTaken, alias, other)
+		}
+	}
+	r.entry(sn).Alias = alias
+	r.prune(sn)
+	return nil
+}
+
+// AddTags adds the tags to the device with the given serial number, the existing tags are kept.
+func (r *Registry) AddTags(sn string, tags ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	en := r.entry(sn)
+	for _, tag := range tags {
+		if tag != "" && !containsString(en.Tags, tag) {
+			en.Tags = append(en.Tags, tag)
+		}
+	}
+	sort.Strings(en.Tags)
+	r.prune(sn)
+}
+
+// RemoveTags removes the tags from the device with the given serial number.
+func (r *Registry) RemoveTags(sn string, tags ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	en := r.entry(sn)
+	kept := en.Tags[:0]
+	for _, tag := range en.Tags {
+		if !containsString(tags, tag) {
+			kept = append(kept, tag)
+		}
+	}
+	en.Tags = kept
+	r.prune(sn)
+}
+
+// Remove removes the device with the given serial number from the registry.
+func (r *Registry) Remove(sn string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, sn)
+}
+
+// Lookup returns the entry of the device with the given serial number.
+func (r *Registry) Lookup(sn string) (RegistryEntry, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	en, ok := r.entries[sn]
+	if !ok {
+		return RegistryEntry{}, false
+	}
+	return copyEntry(en), true
+}
+
+// Entries returns all the entries in the registry in order of serial number.
+func (r *Registry) Entries() []RegistryEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ens := make([]RegistryEntry, 0, len(r.entries))
+	for _, en := range r.entries {
+		ens = append(ens, copyEntry(en))
+	}
+	sort.Slice(ens, func(i, j int) bool {
+		return ens[i].SerialNumber < ens[j].SerialNumber
+	})
+	return ens
+}
+
+// SerialNumberByAlias returns the serial number of the device with the given alias.
+func (r *Registry) SerialNumberByAlias(alias string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.serialByAlias(alias)
+}
+
+// SerialNumbersByTag returns the serial numbers of the devices with the given tag in ascending order.
+func (r *Registry) SerialNumbersByTag(tag string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var sns []string
+	for sn, en := range r.entries {
+		if containsString(en.Tags, tag) {
+			sns = append(sns, sn)
+		}
+	}
+	sort.Strings(sns)
+	return sns
+}
+
+// FindDeviceInfoByAlias finds a connected blink(1) device with the given alias and returns its HID device info.
+func (r *Registry) FindDeviceInfoByAlias(alias string) (*hid.DeviceInfo, error) {
+	sn, ok := r.SerialNumberByAlias(alias)
+	if !ok {
+		return nil, fmt.Errorf("%w for alias %q", ErrDeviceNotFound, alias)
+	}
+
+	r.mu.RLock()
+	source := r.source
+	r.mu.RUnlock()
+	if source == nil {
+		return FindDeviceInfoBySerialNumber(sn)
+	}
+	for _, di := range source() {
+		if IsBlink1Device(di) && di.SerialNumber == sn {
+			return di, nil
+		}
+	}
+	return nil, fmt.Errorf("%w for %q", ErrDeviceNotFound, sn)
+}
+
+// FindDeviceInfosByTag finds all connected blink(1) devices with the given tag and returns their HID device info sorted by serial number.
+func (r *Registry) FindDeviceInfosByTag(tag string) ([]*hid.DeviceInfo, error) {
+	r.mu.RLock()
+	source := r.source
+	r.mu.RUnlock()
+	if source == nil {
+		source = ListDeviceInfo
+	}
+
+	sns := r.SerialNumbersByTag(tag)
+	var infos []*hid.DeviceInfo
+	for _, di := range sortDeviceInfo(source()) {
+		if containsString(sns, di.SerialNumber) {
+			infos = append(infos, di)
+		}
+	}
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("%w for tag %q", ErrDeviceNotFound, tag)
+	}
+	return infos, nil
+}
+
+// OpenDeviceByAlias finds a connected blink(1) device with the given alias and opens it as device.
+func (r *Registry) OpenDeviceByAlias(alias string) (*Device, error) {
+	di, err := r.FindDeviceInfoByAlias(alias)
+	if err != nil {
+		return nil, err
+	}
+	return OpenDevice(di)
+}
+
+// OpenControllerByAlias finds a connected blink(1) device with the given alias and opens it as controller.
+func (r *Registry) OpenControllerByAlias(alias string) (*Controller, error) {
+	di, err := r.FindDeviceInfoByAlias(alias)
+	if err != nil {
+		return nil, err
+	}
+	return OpenController(di)
+}
+
+// OpenControllerByAlias finds a connected blink(1) device with the given alias in the registry at the default path, and opens it as controller.
+func OpenControllerByAlias(alias string) (*Controller, error) {
+	r, err := LoadDefaultRegistry()
+	if err != nil {
+		return nil, err
+	}
+	return r.OpenControllerByAlias(alias)
+}
+
+// entry returns the entry of the serial number, it's created if not exists.
+func (r *Registry) entry(sn string) *RegistryEntry {
+	en, ok := r.entries[sn]
+	if !ok {
+		en = &RegistryEntry{SerialNumber: sn}
+		r.entries[sn] = en
+	}
+	return en
+}
+
+// prune removes the entry of the serial number if it has neither alias nor tags.
+func (r *Registry) prune(sn string) {
+	if en, ok := r.entries[sn]; ok && en.Alias == "" && len(en.Tags) == 0 {
+		delete(r.entries, sn)
+	}
+}
+
+// serialByAlias returns the serial number of the device with the given alias.
+func (r *Registry) serialByAlias(alias string) (string, bool) {
+	for sn, en := range r.entries {
+		if en.Alias == alias {
+			return sn, true
+		}
+	}
+	return "", false
+}
+
+// copyEntry returns a copy of the entry, so the tags are not shared.
+func copyEntry(en *RegistryEntry) RegistryEntry {
+	cp := *en
+	cp.Tags = append([]string(nil), en.Tags...)
+	return cp
+}
+
+// containsString returns true if the slice contains the string.
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
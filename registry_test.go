@@ -0,0 +1,83 @@
+package blink1_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	b1 "github.com/b1ug/blink1-go"
+	hid "github.com/b1ug/gid"
+)
+
+func TestRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "b1-registry")
+	if err != nil {
+		t.Fatalf("TempDir() got unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "conf", "registry.json")
+
+	// missing file is an empty registry
+	r, err := b1.LoadRegistry(path)
+	if err != nil {
+		t.Fatalf("LoadRegistry() got unexpected error: %v", err)
+	}
+	if n := len(r.Entries()); n != 0 {
+		t.Errorf("Entries() of new registry got %d, want 0", n)
+	}
+
+	// aliases and tags
+	if err := r.SetAlias("EMU00001", "desk-alice"); err != nil {
+		t.Fatalf("SetAlias() got unexpected error: %v", err)
+	}
+	if err := r.SetAlias("EMU00002", "desk-alice"); !errors.Is(err, b1.ErrAliasTaken) {
+		t.Errorf("SetAlias() with taken alias got error: %v, want ErrAliasTaken", err)
+	}
+	r.AddTags("EMU00001", "build-status", "office")
+	r.AddTags("EMU00002", "build-status")
+	r.AddTags("EMU00003", "lobby")
+	r.RemoveTags("EMU00003", "lobby")
+	if _, ok := r.Lookup("EMU00003"); ok {
+		t.Errorf("Lookup() of entry without alias and tags should fail")
+	}
+	if err := r.Save(); err != nil {
+		t.Fatalf("Save() got unexpected error: %v", err)
+	}
+	if fis, _ := ioutil.ReadDir(filepath.Dir(path)); len(fis) != 1 || fis[0].Mode().Perm() != 0644 {
+		t.Errorf("Save() left files %v, want only the registry file with mode 0644", fis)
+	}
+
+	// reload
+	r, err = b1.LoadRegistry(path)
+	if err != nil {
+		t.Fatalf("LoadRegistry() got unexpected error: %v", err)
+	}
+	ens := r.Entries()
+	if len(ens) != 2 || ens[0].Alias != "desk-alice" || len(ens[0].Tags) != 2 || ens[1].SerialNumber != "EMU00002" {
+		t.Errorf("Entries() after reload = %v, want 2 entries", ens)
+	}
+
+	// lookups on the given devices
+	r.SetSource(func() []*hid.DeviceInfo {
+		return []*hid.DeviceInfo{
+			b1.NewEmulator(2, "EMU00002").GetDeviceInfo(),
+			b1.NewEmulator(3, "EMU00001").GetDeviceInfo(),
+			b1.NewEmulator(2, "EMU00004").GetDeviceInfo(),
+		}
+	})
+	if di, err := r.FindDeviceInfoByAlias("desk-alice"); err != nil || di.SerialNumber != "EMU00001" {
+		t.Errorf("FindDeviceInfoByAlias() = %v, %v, want EMU00001", di, err)
+	}
+	if _, err := r.FindDeviceInfoByAlias("nobody"); !errors.Is(err, b1.ErrDeviceNotFound) {
+		t.Errorf("FindDeviceInfoByAlias() with unknown alias got error: %v, want ErrDeviceNotFound", err)
+	}
+	infos, err := r.FindDeviceInfosByTag("build-status")
+	if err != nil || len(infos) != 2 || infos[0].SerialNumber != "EMU00001" || infos[1].SerialNumber != "EMU00002" {
+		t.Errorf("FindDeviceInfosByTag() = %v, %v, want EMU00001 and EMU00002", infos, err)
+	}
+	if _, err := r.FindDeviceInfosByTag("lobby"); !errors.Is(err, b1.ErrDeviceNotFound) {
+		t.Errorf("FindDeviceInfosByTag() with unknown tag got error: %v, want ErrDeviceNotFound", err)
+	}
+}
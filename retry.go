@@ -0,0 +1,151 @@
+package blink1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// calibRounds is the number of round trips measured by CalibratePacing.
+const calibRounds = 5
+
+// RetryPolicy controls how the Controller retries a failed operation on the device, e.g. setting or reading a pattern line.
+type RetryPolicy struct {
+	Attempts   int                  // Total number of attempts including the first one, values less than 1 are treated as 1
+	Backoff    time.Duration        // Wait time before the first retry
+	Multiplier float64              // Growth factor of the wait time for each subsequent retry, values less than 1 are treated as 1, i.e. constant backoff
+	MaxBackoff time.Duration        // Upper limit of the wait time, 0 means no limit
+	Jitter     float64              // Random fraction in [0, 1] to spread the wait time by, e.g. 0.2 means ±20%
+	Retryable  func(err error) bool // Reports whether the error is worth retrying, nil means IsRetryableError
+}
+
+// DefaultRetryPolicy returns the retry policy used by Controller by default: 3 attempts with a constant backoff of 30ms.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:   opsTryTimes,
+		Backoff:    opsInterval,
+		Multiplier: 1,
+	}
+}
+
+func (p RetryPolicy) String() string {
+	return fmt.Sprintf("🔁{attempts=%d backoff=%v x%.2f max=%v jitter=%.2f}", p.Attempts, p.Backoff, p.Multiplier, p.MaxBackoff, p.Jitter)
+}
+
+// IsRetryableError reports whether the error may go away by retrying the operation.
+// Errors of closed devices, done contexts, invalid arguments and unsupported features are not retryable, while others like I/O errors are.
+func IsRetryableError(err error) bool {
+	for _, e := range []error{ErrClosed, ErrUnsupported, ErrInvalidArgument, ErrInvalidPosition, ErrInvalidRepeatTimes, ErrInvalidTimeout, context.Canceled, context.DeadlineExceeded} {
+		if errors.Is(err, e) {
+			return false
+		}
+	}
+	return true
+}
+
+// backoff returns the wait time before the n-th retry, starting from 0.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	mul := p.Multiplier
+	if mul < 1 {
+		mul = 1
+	}
+	dur := float64(p.Backoff) * math.Pow(mul, float64(n))
+	if p.MaxBackoff > 0 && dur > float64(p.MaxBackoff) {
+		dur = float64(p.MaxBackoff)
+	}
+	if jit := clampFloat64(p.Jitter, 0, 1); jit > 0 {
+		if r, err := getRandomFloat(1000); err == nil {
+			dur *= 1 + jit*(2*r-1)
+		}
+	}
+	return time.Duration(dur)
+}
+
+// run runs the workload until it succeeds, the error is not retryable, the attempts are used up or the context is done.
+// The workload gets the context carrying the number of retries so far, which is reported to the observer of the device.
+func (p RetryPolicy) run(ctx context.Context, workload func(ctx context.Context) error) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryableError
+	}
+
+	var err error
+	for i := 0; ; i++ {
+		if err = workload(context.WithValue(ctx, retryKey{}, i)); err == nil {
+			// success
+			return nil
+		}
+		if i+1 >= p.Attempts || !retryable(err) {
+			return err
+		}
+		// wait before retry, cool down time, or give up if the context is done
+		if ce := sleepContext(ctx, p.backoff(i)); ce != nil {
+			return fmt.Errorf("%w during retry backoff, last error: %v", ce, err)
+		}
+	}
+}
+
+// SetRetryPolicy sets the retry policy of the controller for operations on pattern lines. Default is DefaultRetryPolicy().
+func (c *Controller) SetRetryPolicy(p RetryPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retry = p
+}
+
+// GetRetryPolicy returns the retry policy of the controller.
+func (c *Controller) GetRetryPolicy() RetryPolicy {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.retry
+}
+
+// SetPacing sets the gap between consecutive commands of multi-command operations like LoadPattern, to avoid errors from the device. Default is 30ms.
+// Negative values are treated as 0, i.e. no gap.
+func (c *Controller) SetPacing(gap time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gap < 0 {
+		gap = 0
+	}
+	c.pacing = gap
+}
+
+// GetPacing returns the gap between consecutive commands of multi-command operations.
+func (c *Controller) GetPacing() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pacing
+}
+
+// CalibratePacing measures the round-trip latency of the device with GetVersion, and sets the pacing to the minimum safe gap between consecutive commands,
+// i.e. twice the slowest round trip, but no more than the default 30ms. It returns the chosen gap.
+func (c *Controller) CalibratePacing() (time.Duration, error) {
+	return c.CalibratePacingContext(context.Background())
+}
+
+// CalibratePacingContext works like CalibratePacing with the given context.
+func (c *Controller) CalibratePacingContext(ctx context.Context) (time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.settleCoalesced()
+
+	var slowest time.Duration
+	for i := 0; i < calibRounds; i++ {
+		start := time.Now()
+		if _, err := c.dev.GetVersionContext(ctx); err != nil {
+			return 0, fmt.Errorf("b1: failed to calibrate pacing: %w", err)
+		}
+		if rtt := time.Since(start); rtt > slowest {
+			slowest = rtt
+		}
+	}
+
+	gap := 2 * slowest
+	if gap > opsInterval {
+		gap = opsInterval
+	}
+	c.pacing = gap
+	return gap, nil
+}
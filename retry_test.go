@@ -0,0 +1,109 @@
+package blink1_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	b1 "github.com/b1ug/blink1-go"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	tr := newFakeTransport(2)
+	tr.failErr = errors.New("io fail")
+	c, err := b1.NewControllerWithTransport(tr)
+	if err != nil {
+		t.Fatalf("NewControllerWithTransport() got unexpected error: %v", err)
+	}
+	defer c.Close()
+
+	if p := c.GetRetryPolicy(); p.Attempts != 3 || p.Backoff != 30*time.Millisecond {
+		t.Errorf("GetRetryPolicy() = %v, want default", p)
+	}
+
+	// retry until attempts are used up
+	var checked int
+	c.SetRetryPolicy(b1.RetryPolicy{
+		Attempts:   4,
+		Backoff:    time.Millisecond,
+		Multiplier: 2,
+		MaxBackoff: 3 * time.Millisecond,
+		Jitter:     0.5,
+		Retryable: func(err error) bool {
+			checked++
+			return b1.IsRetryableError(err)
+		},
+	})
+	seq := b1.StateSequence{b1.NewLightState(b1.ColorRed, 0, b1.LEDAll)}
+	if err := c.LoadPattern(0, 0, seq); !errors.Is(err, tr.failErr) {
+		t.Errorf("LoadPattern() got error: %v, want %v", err, tr.failErr)
+	}
+	if checked != 3 {
+		t.Errorf("Retryable() called %d times, want 3", checked)
+	}
+
+	// give up with the context error if it's done during backoff
+	p := c.GetRetryPolicy()
+	p.Backoff, p.MaxBackoff = time.Hour, 0
+	c.SetRetryPolicy(p)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := c.LoadPatternContext(ctx, 0, 0, seq); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("LoadPatternContext() canceled during backoff got error: %v, want context.DeadlineExceeded", err)
+	}
+	if el := time.Since(start); el > time.Second {
+		t.Errorf("LoadPatternContext() canceled during backoff took %v, want quick return", el)
+	}
+	p.Backoff, p.MaxBackoff = time.Millisecond, 3*time.Millisecond
+	c.SetRetryPolicy(p)
+
+	// no retry for non-retryable errors
+	checked = 0
+	c.GetDevice().Close()
+	if err := c.LoadPattern(0, 0, seq); !errors.Is(err, b1.ErrClosed) {
+		t.Errorf("LoadPattern() after Close got error: %v, want ErrClosed", err)
+	}
+	if checked != 1 {
+		t.Errorf("Retryable() called %d times, want 1", checked)
+	}
+	if b1.IsRetryableError(context.Canceled) || !b1.IsRetryableError(tr.failErr) {
+		t.Errorf("IsRetryableError() got unexpected result")
+	}
+}
+
+func TestController_Pacing(t *testing.T) {
+	c := newEmulatedController(t, 2)
+	defer c.Close()
+
+	if gap := c.GetPacing(); gap != 30*time.Millisecond {
+		t.Errorf("GetPacing() = %v, want 30ms", gap)
+	}
+	gap, err := c.CalibratePacing()
+	if err != nil {
+		t.Fatalf("CalibratePacing() got unexpected error: %v", err)
+	}
+	if gap > 30*time.Millisecond || gap != c.GetPacing() {
+		t.Errorf("CalibratePacing() = %v, GetPacing() = %v, want the same gap <= 30ms", gap, c.GetPacing())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.CalibratePacingContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("CalibratePacingContext() with canceled context got error: %v, want context.Canceled", err)
+	}
+
+	// flash a full pattern without gaps
+	c.SetPacing(0)
+	seq := make(b1.StateSequence, 32)
+	for i := range seq {
+		seq[i] = b1.NewLightState(b1.ColorBlue, 0, b1.LEDAll)
+	}
+	start := time.Now()
+	if err := c.LoadPattern(0, 31, seq); err != nil {
+		t.Fatalf("LoadPattern() got unexpected error: %v", err)
+	}
+	if el := time.Since(start); el > 500*time.Millisecond {
+		t.Errorf("LoadPattern() without pacing took %v, want much less than 1s", el)
+	}
+}
@@ -67,6 +67,29 @@ func (st PatternState) String() string {
 	return fmt.Sprintf("%s(playing=%t cur=%d loop=[%d,%d) left=%d)", convPlayingToEmoji(st.IsPlaying), st.IsPlaying, st.CurrentPosition, st.StartPosition, st.EndPosition, st.RepeatTimes)
 }
 
+// DeviceStartupParams is the startup (power-on) behavior of blink(1) device for low-level APIs.
+type DeviceStartupParams struct {
+	Enabled      bool // Play the pattern loop on power-up
+	LoopStartPos uint // Loop start position, inclusive
+	LoopEndPos   uint // Loop end position, inclusive
+	RepeatTimes  uint // How many times to repeat, 0 means infinite
+}
+
+func (sp DeviceStartupParams) String() string {
+	return fmt.Sprintf("🔌{enabled=%t loop=[%d,%d] repeat=%d}", sp.Enabled, sp.LoopStartPos, sp.LoopEndPos, sp.RepeatTimes)
+}
+
+// DeviceEEPROMLayout is the typed view of the known fields in the EEPROM of blink(1) device for low-level APIs.
+type DeviceEEPROMLayout struct {
+	OscCal       byte    // Oscillator calibration value, at address 0
+	BootMode     byte    // Boot mode flags, at address 1
+	SerialNumber [4]byte // Serial number bytes, at address 2-5
+}
+
+func (ly DeviceEEPROMLayout) String() string {
+	return fmt.Sprintf("💾{osccal=0x%02X boot=0x%02X sn=%X}", ly.OscCal, ly.BootMode, ly.SerialNumber)
+}
+
 // DeviceLightState is a blink(1) light state for low-level APIs.
 type DeviceLightState struct {
 	R, G, B      byte     // RGB values
@@ -0,0 +1,191 @@
+package blink1
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	hid "github.com/b1ug/gid"
+)
+
+var (
+	errNoRegistry = errors.New("b1: no registry for alias selector")
+)
+
+// Selector selects blink(1) devices by an expression, which is a comma-separated list of terms, and the union of the devices matched by any term is selected. The terms are:
+//
+//	first         the first device
+//	all, *        all devices
+//	index:N       the N-th device, starting from 0
+//	serial:GLOB   devices with serial number matching the glob pattern, e.g. serial:2000*
+//	gen>=N        devices of generation compared with N, the operator can be one of = == != > >= < <=
+//	alias:NAME    the device with the alias in the registry
+//
+// The devices are ordered by serial number, like ListDeviceInfo().
+type Selector struct {
+	expr     string
+	terms    []selectorTerm
+	registry *Registry
+}
+
+// selectorTerm matches the index-th device in the ordered device list.
+type selectorTerm func(s *Selector, index int, di *hid.DeviceInfo) (bool, error)
+
+// ParseSelector parses the selector expression.
+func ParseSelector(expr string) (*Selector, error) {
+	s := &Selector{expr: expr}
+	for _, raw := range strings.Split(expr, ",") {
+		term, err := parseSelectorTerm(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidSelector, expr, err)
+		}
+		s.terms = append(s.terms, term)
+	}
+	return s, nil
+}
+
+func (s *Selector) String() string {
+	return s.expr
+}
+
+// SetRegistry sets the registry to resolve the alias terms.
+func (s *Selector) SetRegistry(r *Registry) {
+	s.registry = r
+}
+
+// Select returns the devices matched by the selector from the given devices in order of serial number.
+// An error wrapping ErrDeviceNotFound will be returned if nothing matches.
+func (s *Selector) Select(infos []*hid.DeviceInfo) ([]*hid.DeviceInfo, error) {
+	var (
+		ordered = sortDeviceInfo(infos)
+		matched []*hid.DeviceInfo
+	)
+	for i, di := range ordered {
+		for _, term := range s.terms {
+			ok, err := term(s, i, di)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				matched = append(matched, di)
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("%w for selector %q", ErrDeviceNotFound, s.expr)
+	}
+	return matched, nil
+}
+
+// SelectDeviceInfo parses the selector expression and returns the matched blink(1) devices which are connected to the system in order of serial number.
+// The alias terms are resolved with the registry at the default path.
+func SelectDeviceInfo(expr string) ([]*hid.DeviceInfo, error) {
+	s, err := ParseSelector(expr)
+	if err != nil {
+		return nil, err
+	}
+	if s.hasAlias() {
+		r, err := LoadDefaultRegistry()
+		if err != nil {
+			return nil, err
+		}
+		s.SetRegistry(r)
+	}
+	return s.Select(ListDeviceInfo())
+}
+
+// hasAlias returns true if the expression contains alias terms.
+func (s *Selector) hasAlias() bool {
+	for _, raw := range strings.Split(s.expr, ",") {
+		if strings.HasPrefix(strings.TrimSpace(raw), "alias:") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSelectorTerm parses a single term of the selector expression.
+func parseSelectorTerm(raw string) (selectorTerm, error) {
+	switch {
+	case raw == "":
+		return nil, errors.New("empty term")
+	case raw == "first":
+		return matchIndex(0), nil
+	case raw == "all" || raw == "*":
+		return func(*Selector, int, *hid.DeviceInfo) (bool, error) { return true, nil }, nil
+	case strings.HasPrefix(raw, "index:"):
+		n, err := strconv.Atoi(strings.TrimPrefix(raw, "index:"))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("bad index in %q", raw)
+		}
+		return matchIndex(n), nil
+	case strings.HasPrefix(raw, "serial:"):
+		pat := strings.ToUpper(strings.TrimPrefix(raw, "serial:"))
+		if _, err := path.Match(pat, ""); err != nil || pat == "" {
+			return nil, fmt.Errorf("bad serial pattern in %q", raw)
+		}
+		return func(_ *Selector, _ int, di *hid.DeviceInfo) (bool, error) {
+			return path.Match(pat, strings.ToUpper(di.SerialNumber))
+		}, nil
+	case strings.HasPrefix(raw, "alias:"):
+		alias := strings.TrimPrefix(raw, "alias:")
+		if alias == "" {
+			return nil, fmt.Errorf("empty alias in %q", raw)
+		}
+		return func(s *Selector, _ int, di *hid.DeviceInfo) (bool, error) {
+			if s.registry == nil {
+				return false, errNoRegistry
+			}
+			sn, ok := s.registry.SerialNumberByAlias(alias)
+			return ok && sn == di.SerialNumber, nil
+		}, nil
+	case strings.HasPrefix(raw, "gen"):
+		return parseGenTerm(raw)
+	default:
+		return nil, fmt.Errorf("unknown term %q", raw)
+	}
+}
+
+// parseGenTerm parses the generation comparison term, e.g. gen>=2.
+func parseGenTerm(raw string) (selectorTerm, error) {
+	rest := strings.TrimPrefix(raw, "gen")
+	for _, op := range []string{">=", "<=", "==", "!=", ">", "<", "="} {
+		if !strings.HasPrefix(rest, op) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(rest, op))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("bad generation in %q", raw)
+		}
+		cmp := func(gen int) bool {
+			switch op {
+			case ">=":
+				return gen >= n
+			case "<=":
+				return gen <= n
+			case "!=":
+				return gen != n
+			case ">":
+				return gen > n
+			case "<":
+				return gen < n
+			default:
+				return gen == n
+			}
+		}
+		return func(_ *Selector, _ int, di *hid.DeviceInfo) (bool, error) {
+			return cmp(int(di.VersionNumber)), nil
+		}, nil
+	}
+	return nil, fmt.Errorf("bad operator in %q", raw)
+}
+
+// matchIndex returns a term matching the n-th device.
+func matchIndex(n int) selectorTerm {
+	return func(_ *Selector, index int, _ *hid.DeviceInfo) (bool, error) {
+		return index == n, nil
+	}
+}
@@ -0,0 +1,77 @@
+package blink1_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	b1 "github.com/b1ug/blink1-go"
+	hid "github.com/b1ug/gid"
+)
+
+func TestSelector(t *testing.T) {
+	infos := []*hid.DeviceInfo{
+		b1.NewEmulator(3, "3000C001").GetDeviceInfo(),
+		b1.NewEmulator(1, "1000A001").GetDeviceInfo(),
+		b1.NewEmulator(2, "2000B002").GetDeviceInfo(),
+		b1.NewEmulator(2, "2000B001").GetDeviceInfo(),
+	}
+	reg := b1.NewRegistry()
+	_ = reg.SetAlias("2000B002", "lobby")
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"first", "1000A001"},
+		{"all", "1000A001,2000B001,2000B002,3000C001"},
+		{"*", "1000A001,2000B001,2000B002,3000C001"},
+		{"index:2", "2000B002"},
+		{"serial:2000*", "2000B001,2000B002"},
+		{"serial:2000b00?", "2000B001,2000B002"},
+		{"gen>=2", "2000B001,2000B002,3000C001"},
+		{"gen<2", "1000A001"},
+		{"gen=3", "3000C001"},
+		{"gen!=2", "1000A001,3000C001"},
+		{"alias:lobby", "2000B002"},
+		{"alias:lobby, first, gen==3", "1000A001,2000B002,3000C001"},
+		{"index:1,serial:2000B001", "2000B001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			s, err := b1.ParseSelector(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseSelector(%q) got unexpected error: %v", tt.expr, err)
+			}
+			s.SetRegistry(reg)
+			got, err := s.Select(infos)
+			if err != nil {
+				t.Fatalf("Select() got unexpected error: %v", err)
+			}
+			var sns []string
+			for _, di := range got {
+				sns = append(sns, di.SerialNumber)
+			}
+			if strings.Join(sns, ",") != tt.want {
+				t.Errorf("Select(%q) = %v, want %s", tt.expr, sns, tt.want)
+			}
+		})
+	}
+
+	// invalid expressions
+	for _, expr := range []string{"", "last", "index:x", "index:-1", "gen~2", "gen>=x", "serial:[", "alias:", "first,,all"} {
+		if _, err := b1.ParseSelector(expr); !errors.Is(err, b1.ErrInvalidSelector) {
+			t.Errorf("ParseSelector(%q) got error: %v, want ErrInvalidSelector", expr, err)
+		}
+	}
+
+	// nothing matches
+	s, _ := b1.ParseSelector("index:9,serial:9*")
+	if _, err := s.Select(infos); !errors.Is(err, b1.ErrDeviceNotFound) {
+		t.Errorf("Select() with no match got error: %v, want ErrDeviceNotFound", err)
+	}
+	s, _ = b1.ParseSelector("alias:lobby")
+	if _, err := s.Select(infos); err == nil {
+		t.Errorf("Select() with alias but no registry should fail")
+	}
+}
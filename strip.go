@@ -0,0 +1,218 @@
+package blink1
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"math"
+	"sync"
+	"time"
+)
+
+// StripEffect is a host-side effect of Strip, it returns the frame of colors for the given step and strip length, or nil if the effect is finished.
+type StripEffect func(step, n int) []color.Color
+
+// Strip treats the LEDs of multiple blink(1) devices as a virtual LED strip, i.e. 2×N pixels for N devices.
+// Pixel 2k is LED1 and pixel 2k+1 is LED2 of the k-th device in the configured order.
+type Strip struct {
+	mu     sync.Mutex
+	g      *Group        // controllers in strip order
+	fade   time.Duration // fade time of each frame
+	pixels []color.Color // last frame sent to the devices, nil for unknown pixels
+}
+
+// NewStrip creates a strip of the devices in the group, in the order of the given serial numbers, or in the order of the group if no serial numbers are given.
+// All the serial numbers must be in the group without duplicates, and the devices not listed are excluded from the strip.
+func NewStrip(g *Group, order ...string) (*Strip, error) {
+	ctrls := g.Controllers()
+	if len(order) > 0 {
+		bySN := make(map[string]*Controller, len(ctrls))
+		for _, c := range ctrls {
+			bySN[c.dev.sn] = c
+		}
+		ctrls = make([]*Controller, 0, len(order))
+		seen := make(map[string]bool, len(order))
+		for _, sn := range order {
+			c, ok := bySN[sn]
+			if !ok {
+				return nil, fmt.Errorf("%w in group for %q", ErrDeviceNotFound, sn)
+			}
+			if seen[sn] {
+				return nil, fmt.Errorf("%w: duplicate serial number %q in strip order", ErrInvalidArgument, sn)
+			}
+			seen[sn] = true
+			ctrls = append(ctrls, c)
+		}
+	}
+	return &Strip{
+		g:      &Group{ctrls: ctrls},
+		pixels: make([]color.Color, 2*len(ctrls)),
+	}, nil
+}
+
+func (s *Strip) String() string {
+	return fmt.Sprintf("💡{len=%d order=%v}", s.Len(), s.g.SerialNumbers())
+}
+
+// Len returns the number of pixels of the strip.
+func (s *Strip) Len() int {
+	return len(s.pixels)
+}
+
+// SetFadeTime sets the fade time of each pixel update. Default is 0, i.e. immediately.
+func (s *Strip) SetFadeTime(fade time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fade = fade
+}
+
+// Pixel returns the color of the pixel last sent to the device, or nil if it's unknown.
+func (s *Strip) Pixel(i int) color.Color {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i < 0 || i >= len(s.pixels) {
+		return nil
+	}
+	return s.pixels[i]
+}
+
+// SetPixel sets the color of the i-th pixel.
+func (s *Strip) SetPixel(i int, cl color.Color) error {
+	return s.SetPixelContext(context.Background(), i, cl)
+}
+
+// SetPixelContext works like SetPixel with the given context.
+func (s *Strip) SetPixelContext(ctx context.Context, i int, cl color.Color) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i < 0 || i >= len(s.pixels) {
+		return fmt.Errorf("%w: pixel %d is out of range [0, %d)", ErrInvalidArgument, i, len(s.pixels))
+	}
+	frame := append([]color.Color(nil), s.pixels...)
+	frame[i] = cl
+	return s.push(ctx, frame)
+}
+
+// PushFrame sets the colors of all pixels, the length of the frame must be the same as the strip.
+// Only the changed pixels are sent to the devices, and the devices are updated concurrently.
+func (s *Strip) PushFrame(frame []color.Color) error {
+	return s.PushFrameContext(context.Background(), frame)
+}
+
+// PushFrameContext works like PushFrame with the given context.
+func (s *Strip) PushFrameContext(ctx context.Context, frame []color.Color) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(frame) != len(s.pixels) {
+		return fmt.Errorf("%w: frame length %d is not strip length %d", ErrInvalidArgument, len(frame), len(s.pixels))
+	}
+	return s.push(ctx, frame)
+}
+
+// ShowProgress shows a progress bar of the given fraction in [0, 1] on the strip, the partially filled pixel is dimmed.
+func (s *Strip) ShowProgress(fraction float64, fg, bg color.Color) error {
+	return s.PushFrame(ProgressFrame(s.Len(), fraction, fg, bg))
+}
+
+// Run runs the effect on the strip, one frame per interval, until the effect is finished or the context is done.
+func (s *Strip) Run(ctx context.Context, eff StripEffect, interval time.Duration) error {
+	for step := 0; ; step++ {
+		frame := eff(step, s.Len())
+		if frame == nil {
+			return nil
+		}
+		if err := s.PushFrameContext(ctx, frame); err != nil {
+			return err
+		}
+		if err := sleepContext(ctx, interval); err != nil {
+			return err
+		}
+	}
+}
+
+// push sends the changed pixels of the frame to the devices concurrently, and keeps the sent ones.
+func (s *Strip) push(ctx context.Context, frame []color.Color) error {
+	fade := s.fade
+	return s.g.Each(ctx, func(ctx context.Context, c *Controller) error {
+		k := s.indexOf(c)
+		for j, led := range []LEDIndex{LED1, LED2} {
+			i := 2*k + j
+			if frame[i] == nil || (s.pixels[i] != nil && ColorToHex(s.pixels[i]) == ColorToHex(frame[i])) {
+				continue
+			}
+			if err := c.PlayStateContext(ctx, LightState{Color: frame[i], LED: led, FadeTime: fade}); err != nil {
+				return err
+			}
+			s.pixels[i] = frame[i]
+		}
+		return nil
+	})
+}
+
+// indexOf returns the index of the controller in the strip order.
+func (s *Strip) indexOf(c *Controller) int {
+	for k, sc := range s.g.ctrls {
+		if sc == c {
+			return k
+		}
+	}
+	return -1
+}
+
+// ChaseEffect returns an effect which moves a single pixel of the given color along the strip over the background color, and loops forever.
+func ChaseEffect(cl, bg color.Color) StripEffect {
+	return func(step, n int) []color.Color {
+		frame := fillFrame(n, bg)
+		if n > 0 {
+			frame[step%n] = cl
+		}
+		return frame
+	}
+}
+
+// WaveEffect returns an effect which moves a sine wave of brightness of the given color along the strip, with the period in pixels, and loops forever.
+func WaveEffect(cl color.Color, period int) StripEffect {
+	if period <= 0 {
+		period = 1
+	}
+	return func(step, n int) []color.Color {
+		frame := make([]color.Color, n)
+		for i := range frame {
+			phase := 2 * math.Pi * float64(i-step) / float64(period)
+			frame[i] = scaleColor(cl, (math.Sin(phase)+1)/2)
+		}
+		return frame
+	}
+}
+
+// ProgressFrame returns a frame of a progress bar of the given fraction in [0, 1] for a strip of n pixels, the partially filled pixel is dimmed.
+func ProgressFrame(n int, fraction float64, fg, bg color.Color) []color.Color {
+	frame := fillFrame(n, bg)
+	filled := clampFloat64(fraction, 0, 1) * float64(n)
+	for i := 0; i < n && float64(i) < filled; i++ {
+		if part := filled - float64(i); part >= 1 {
+			frame[i] = fg
+		} else {
+			frame[i] = scaleColor(fg, part)
+		}
+	}
+	return frame
+}
+
+// fillFrame returns a frame of n pixels with the same color.
+func fillFrame(n int, cl color.Color) []color.Color {
+	frame := make([]color.Color, n)
+	for i := range frame {
+		frame[i] = cl
+	}
+	return frame
+}
+
+// scaleColor returns the color with brightness scaled by the factor in [0, 1].
+func scaleColor(cl color.Color, factor float64) color.Color {
+	r, g, b := convColorToRGB(cl)
+	f := clampFloat64(factor, 0, 1)
+	return convRGBToColor(uint8(math.Round(float64(r)*f)), uint8(math.Round(float64(g)*f)), uint8(math.Round(float64(b)*f)))
+}
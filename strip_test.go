@@ -0,0 +1,102 @@
+package blink1_test
+
+import (
+	"context"
+	"errors"
+	"image/color"
+	"testing"
+
+	b1 "github.com/b1ug/blink1-go"
+)
+
+func TestStrip(t *testing.T) {
+	var ctrls []*b1.Controller
+	for _, sn := range []string{"EMU00001", "EMU00002"} {
+		c, err := b1.NewControllerWithTransport(b1.NewEmulator(2, sn))
+		if err != nil {
+			t.Fatalf("NewControllerWithTransport() got unexpected error: %v", err)
+		}
+		ctrls = append(ctrls, c)
+	}
+	g := b1.NewGroup(ctrls...)
+	defer g.Close()
+	g.SetGammaCorrection(false)
+
+	if _, err := b1.NewStrip(g, "EMU00002", "EMU00009"); !errors.Is(err, b1.ErrDeviceNotFound) {
+		t.Errorf("NewStrip() with unknown serial got error: %v, want ErrDeviceNotFound", err)
+	}
+	if _, err := b1.NewStrip(g, "EMU00001", "EMU00002", "EMU00001"); !errors.Is(err, b1.ErrInvalidArgument) {
+		t.Errorf("NewStrip() with duplicate serial got error: %v, want ErrInvalidArgument", err)
+	}
+	s, err := b1.NewStrip(g, "EMU00002", "EMU00001")
+	if err != nil {
+		t.Fatalf("NewStrip() got unexpected error: %v", err)
+	}
+	if s.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", s.Len())
+	}
+
+	// readFrame reads the colors of the strip from the devices in strip order
+	readFrame := func() []string {
+		var hexes []string
+		for _, c := range []*b1.Controller{ctrls[1], ctrls[0]} {
+			for _, led := range []b1.LEDIndex{b1.LED1, b1.LED2} {
+				cl, err := c.ReadColor(led)
+				if err != nil {
+					t.Fatalf("ReadColor() got unexpected error: %v", err)
+				}
+				hexes = append(hexes, b1.ColorToHex(cl))
+			}
+		}
+		return hexes
+	}
+	checkFrame := func(name string, want ...string) {
+		t.Helper()
+		got := readFrame()
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: frame = %v, want %v", name, got, want)
+				return
+			}
+		}
+	}
+
+	// set pixel and push frame
+	if err := s.SetPixel(1, b1.ColorRed); err != nil {
+		t.Fatalf("SetPixel() got unexpected error: %v", err)
+	}
+	checkFrame("SetPixel", "#000000", "#FF0000", "#000000", "#000000")
+	if err := s.SetPixel(4, b1.ColorRed); !errors.Is(err, b1.ErrInvalidArgument) {
+		t.Errorf("SetPixel(4) got error: %v, want ErrInvalidArgument", err)
+	}
+	if err := s.PushFrame([]color.Color{b1.ColorRed}); !errors.Is(err, b1.ErrInvalidArgument) {
+		t.Errorf("PushFrame() with short frame got error: %v, want ErrInvalidArgument", err)
+	}
+	if err := s.PushFrame([]color.Color{b1.ColorBlue, b1.ColorGreen, b1.ColorRed, b1.ColorWhite}); err != nil {
+		t.Fatalf("PushFrame() got unexpected error: %v", err)
+	}
+	checkFrame("PushFrame", "#0000FF", "#00FF00", "#FF0000", "#FFFFFF")
+
+	// effects
+	if err := s.ShowProgress(0.625, b1.ColorWhite, b1.ColorBlack); err != nil {
+		t.Fatalf("ShowProgress() got unexpected error: %v", err)
+	}
+	checkFrame("ShowProgress", "#FFFFFF", "#FFFFFF", "#808080", "#000000")
+
+	chase := b1.ChaseEffect(b1.ColorRed, b1.ColorBlack)
+	limited := func(step, n int) []color.Color {
+		if step > 2 {
+			return nil
+		}
+		return chase(step, n)
+	}
+	if err := s.Run(context.Background(), limited, 0); err != nil {
+		t.Fatalf("Run() got unexpected error: %v", err)
+	}
+	checkFrame("ChaseEffect", "#000000", "#000000", "#FF0000", "#000000")
+
+	wave := b1.WaveEffect(b1.ColorBlue, 4)(1, 4)
+	if b1.ColorToHex(wave[2]) != "#0000FF" || b1.ColorToHex(wave[0]) != "#000000" {
+		t.Errorf("WaveEffect() frame = %v, want peak at pixel 2", wave)
+	}
+}
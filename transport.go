@@ -0,0 +1,65 @@
+package blink1
+
+import (
+	"fmt"
+
+	hid "github.com/b1ug/gid"
+)
+
+// Transport is the channel used by Device to exchange HID feature reports with a blink(1) device.
+// The default implementation is backed by the b1ug/gid package, but any backend that is able to send and receive feature reports can be used,
+// e.g. an in-process fake for testing or a network proxy.
+type Transport interface {
+	// WriteFeature sends a feature report to the device, the first byte of the buffer is the report ID.
+	// Implementations should return an error wrapping ErrDisconnected if the device is gone, and likewise for ReadFeature.
+	WriteFeature(buf []byte) error
+	// ReadFeature gets a feature report from the device into the buffer, the first byte of the buffer should be set to the report ID.
+	ReadFeature(buf []byte) (int, error)
+	// Close closes the transport and releases the kept resources.
+	Close()
+	// GetDeviceInfo returns the HID device info of the device behind the transport, it's used to identify the device.
+	GetDeviceInfo() *hid.DeviceInfo
+}
+
+// hidTransport is the default Transport backed by the b1ug/gid package.
+type hidTransport struct {
+	info *hid.DeviceInfo
+	dev  hid.Device
+}
+
+// openHIDTransport opens the HID device described by the device info as a transport.
+func openHIDTransport(info *hid.DeviceInfo) (*hidTransport, error) {
+	dev, err := info.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &hidTransport{info: info, dev: dev}, nil
+}
+
+func (t *hidTransport) WriteFeature(buf []byte) error {
+	return t.classify(t.dev.WriteFeature(buf))
+}
+
+func (t *hidTransport) ReadFeature(buf []byte) (int, error) {
+	n, err := t.dev.ReadFeature(buf)
+	return n, t.classify(err)
+}
+
+func (t *hidTransport) Close() {
+	t.dev.Close()
+}
+
+func (t *hidTransport) GetDeviceInfo() *hid.DeviceInfo {
+	return t.info
+}
+
+// classify wraps the I/O error as ErrDisconnected if the device is no longer found by its path.
+func (t *hidTransport) classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, le := hid.ByPath(t.info.Path); le != nil {
+		return fmt.Errorf("%w: %v", ErrDisconnected, err)
+	}
+	return err
+}
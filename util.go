@@ -6,6 +6,7 @@ package blink1
 // methods in this file primarily serve as internal helper functions
 
 import (
+	"context"
 	"crypto/rand"
 	"errors"
 	"fmt"
@@ -25,6 +26,12 @@ const (
 	report3ID   = byte(0x02)            // for mk3+
 	maxPattern  = uint(12)              // for mk1
 	maxPattern2 = uint(32)              // for mk2+
+	maxNote     = uint(10)              // for mk3+
+	noteSize    = 100                   // bytes of a note, for mk3+
+	noteChunk   = 50                    // bytes of a note transferred in one report, for mk3+
+	chipIDSize  = 8                     // bytes of chip unique ID, for mk3+
+	maxEEPROM   = uint(0x100)           // the address of EEPROM is a byte
+	minBootVer  = 204                   // the minimum firmware version of mk2 for startup params
 	maxFadeMsec = uint(0xffff * 10)     // 10 min 55 sec 350 msec
 	maxRepeat   = uint(0xff)            // 255
 	minTimeDur  = 10 * time.Millisecond // the minimum duration for time intervals, any duration shorter than this will be interpreted by the device as having no specified time interval
@@ -32,6 +39,14 @@ const (
 	opsTryTimes = 3                     // the number of times to attempt an operation before giving up
 )
 
+// known addresses of the EEPROM layout
+const (
+	eeAddrOscCal   = 0 // oscillator calibration value
+	eeAddrBootMode = 1 // boot mode flags
+	eeAddrSerial   = 2 // 4 bytes of serial number
+	eeAddrPattern  = 6 // the start of pattern storage
+)
+
 var (
 	// common values
 	durZero  time.Duration
@@ -208,18 +223,25 @@ func convLightState(st LightState) DeviceLightState {
 	}
 }
 
-// retryWorkload retries the specified workload until it succeeds or the retry limit is reached.
-func retryWorkload(workload func() error) error {
-	var err error
-	for i := 0; i < opsTryTimes; i++ {
-		if err = workload(); err == nil {
-			// success
-			return nil
-		}
-		// wait before retry, cool down time
-		time.Sleep(opsInterval)
+// sleepContext pauses for the specified duration, or returns the error of the context once it's done.
+func sleepContext(ctx context.Context, dur time.Duration) error {
+	if dur <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(dur)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// drainTicks discards the signals sent to the tickle channel until it's closed, so the senders are not blocked after the tickle worker quits.
+func drainTicks(tickCh <-chan struct{}) {
+	for range tickCh {
 	}
-	return err
 }
 
 // Migrated from https://github.com/todbot/blink1-tool/blob/92661e6d731b46d4bf82e2506c105c5fe433b57d/blink1-lib.c#L676-L700
@@ -0,0 +1,145 @@
+package blink1
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	hid "github.com/b1ug/gid"
+)
+
+// DeviceEventType represents the type of hotplug event of blink(1) devices.
+type DeviceEventType int
+
+const (
+	// DeviceAdded represents a blink(1) device is plugged in, or it's already connected when the watcher starts
+	DeviceAdded DeviceEventType = iota + 1
+	// DeviceRemoved represents a blink(1) device is unplugged
+	DeviceRemoved
+)
+
+// String returns a string representation of DeviceEventType.
+func (t DeviceEventType) String() string {
+	switch t {
+	case DeviceAdded:
+		return "Added"
+	case DeviceRemoved:
+		return "Removed"
+	default:
+		return "Unknown"
+	}
+}
+
+// DeviceEvent is a hotplug event of blink(1) device emitted by Watcher.
+type DeviceEvent struct {
+	Type         DeviceEventType // Type of the event
+	SerialNumber string          // Serial number of the device, which is the key to identify devices
+	Info         *hid.DeviceInfo // HID device info of the device, for removed devices it's the last known info
+}
+
+func (ev DeviceEvent) String() string {
+	return fmt.Sprintf("🔔{%s sn=%s}", ev.Type, ev.SerialNumber)
+}
+
+// Watcher watches the blink(1) devices connected to the system by polling the enumeration, and emits hotplug events keyed by serial number.
+type Watcher struct {
+	mu       sync.Mutex
+	interval time.Duration
+	source   func() []*hid.DeviceInfo
+}
+
+// NewWatcher creates a watcher which polls the connected blink(1) devices with ListDeviceInfo() at the given interval. If the interval is not positive, 1 second will be used.
+func NewWatcher(interval time.Duration) *Watcher {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &Watcher{
+		interval: interval,
+		source:   ListDeviceInfo,
+	}
+}
+
+// SetSource sets the function to enumerate blink(1) devices, it's ListDeviceInfo() by default.
+// It takes effect on the next poll, and it's useful for other backends of enumeration.
+func (w *Watcher) SetSource(source func() []*hid.DeviceInfo) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.source = source
+}
+
+// Watch starts watching the devices in a goroutine and returns the channel of events.
+// The devices already connected are emitted as DeviceAdded events on the first poll.
+// A device replugged between two polls is emitted as a DeviceRemoved event followed by a DeviceAdded event, if its HID device path has changed.
+// The watching stops and the channel is closed when the context is canceled.
+func (w *Watcher) Watch(ctx context.Context) <-chan DeviceEvent {
+	evCh := make(chan DeviceEvent, 16)
+	go func() {
+		defer close(evCh)
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		known := make(map[string]*hid.DeviceInfo)
+		for {
+			for _, ev := range w.poll(known) {
+				select {
+				case evCh <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return evCh
+}
+
+// poll enumerates the devices, updates the known devices and returns the events of changes in order of serial number.
+func (w *Watcher) poll(known map[string]*hid.DeviceInfo) (evs []DeviceEvent) {
+	w.mu.Lock()
+	source := w.source
+	w.mu.Unlock()
+
+	// collect current devices
+	infos := source()
+	curr := make(map[string]*hid.DeviceInfo, len(infos))
+	for _, di := range infos {
+		if IsBlink1Device(di) {
+			curr[di.SerialNumber] = di
+		}
+	}
+
+	// removed or replugged devices
+	for _, sn := range sortedSerials(known) {
+		old := known[sn]
+		if di, ok := curr[sn]; !ok || di.Path != old.Path {
+			evs = append(evs, DeviceEvent{Type: DeviceRemoved, SerialNumber: sn, Info: old})
+			delete(known, sn)
+		}
+	}
+
+	// added devices
+	for _, sn := range sortedSerials(curr) {
+		if _, ok := known[sn]; !ok {
+			evs = append(evs, DeviceEvent{Type: DeviceAdded, SerialNumber: sn, Info: curr[sn]})
+			known[sn] = curr[sn]
+		}
+	}
+	return evs
+}
+
+// sortedSerials returns the serial numbers of the devices in ascending order.
+func sortedSerials(m map[string]*hid.DeviceInfo) []string {
+	sns := make([]string, 0, len(m))
+	for sn := range m {
+		sns = append(sns, sn)
+	}
+	sort.Strings(sns)
+	return sns
+}
@@ -0,0 +1,67 @@
+package blink1_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	b1 "github.com/b1ug/blink1-go"
+	hid "github.com/b1ug/gid"
+)
+
+func TestWatcher_Watch(t *testing.T) {
+	newInfo := func(sn, path string) *hid.DeviceInfo {
+		return &hid.DeviceInfo{Path: path, VendorID: 0x27B8, ProductID: 0x01ED, VersionNumber: 2, SerialNumber: sn}
+	}
+
+	var (
+		mu    sync.Mutex
+		infos = []*hid.DeviceInfo{newInfo("B", "/b"), newInfo("A", "/a"), {Path: "/x", VendorID: 0x1234, SerialNumber: "X"}}
+	)
+	w := b1.NewWatcher(10 * time.Millisecond)
+	w.SetSource(func() []*hid.DeviceInfo {
+		mu.Lock()
+		defer mu.Unlock()
+		return infos
+	})
+	setInfos := func(ls ...*hid.DeviceInfo) {
+		mu.Lock()
+		defer mu.Unlock()
+		infos = ls
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	evCh := w.Watch(ctx)
+	expect := func(typ b1.DeviceEventType, sn string) {
+		t.Helper()
+		select {
+		case ev := <-evCh:
+			if ev.Type != typ || ev.SerialNumber != sn || ev.Info == nil {
+				t.Errorf("Watch() got %v, want %s of %s", ev, typ, sn)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Watch() got no event, want %s of %s", typ, sn)
+		}
+	}
+
+	// existing devices
+	expect(b1.DeviceAdded, "A")
+	expect(b1.DeviceAdded, "B")
+
+	// unplug and plug
+	setInfos(newInfo("B", "/b"))
+	expect(b1.DeviceRemoved, "A")
+	setInfos(newInfo("B", "/b"), newInfo("C", "/c"))
+	expect(b1.DeviceAdded, "C")
+
+	// replug with new path
+	setInfos(newInfo("B", "/b2"), newInfo("C", "/c"))
+	expect(b1.DeviceRemoved, "B")
+	expect(b1.DeviceAdded, "B")
+
+	// stop watching
+	cancel()
+	for range evCh {
+	}
+}